@@ -0,0 +1,352 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/yuin/goldmark/ast"
+	ext_ast "github.com/yuin/goldmark/extension/ast"
+	"github.com/yuin/goldmark/text"
+)
+
+// styledSpan 是带内联样式的行内容器，用于输出文字颜色、背景高亮、字号、下划线以及上下标。
+// Tag 为空时输出 <span>，上下标分别为 "sup" 和 "sub"
+type styledSpan struct {
+	ast.BaseInline
+	Tag   string
+	Style string
+}
+
+var kindStyledSpan = ast.NewNodeKind("StyledSpan")
+
+func (n *styledSpan) Kind() ast.NodeKind {
+	return kindStyledSpan
+}
+
+func (n *styledSpan) Dump(source []byte, level int) {
+	ast.DumpHelper(n, source, level, map[string]string{"Tag": n.Tag, "Style": n.Style}, nil)
+}
+
+// calloutNode 是提示框，子节点是提示框的内容，Variant 是提示框类型 (如 "tip")
+type calloutNode struct {
+	ast.BaseBlock
+	Variant string
+	Title   string
+}
+
+var kindCallout = ast.NewNodeKind("Callout")
+
+func (n *calloutNode) Kind() ast.NodeKind {
+	return kindCallout
+}
+
+func (n *calloutNode) Dump(source []byte, level int) {
+	ast.DumpHelper(n, source, level, map[string]string{"Variant": n.Variant, "Title": n.Title}, nil)
+}
+
+// figureNode 是单独成段的图片，第一个子节点为 ast.Image，之后是可选的 figureCaptionNode
+type figureNode struct {
+	ast.BaseBlock
+}
+
+// figureCaptionNode 是图片说明，Label 为自动生成的编号 (如 "图 1")
+type figureCaptionNode struct {
+	ast.BaseBlock
+	Label string
+}
+
+var kindFigureCaption = ast.NewNodeKind("FigureCaption")
+
+func (n *figureCaptionNode) Kind() ast.NodeKind {
+	return kindFigureCaption
+}
+
+func (n *figureCaptionNode) Dump(source []byte, level int) {
+	ast.DumpHelper(n, source, level, map[string]string{"Label": n.Label}, nil)
+}
+
+var kindFigure = ast.NewNodeKind("Figure")
+
+func (n *figureNode) Kind() ast.NodeKind {
+	return kindFigure
+}
+
+func (n *figureNode) Dump(source []byte, level int) {
+	ast.DumpHelper(n, source, level, nil, nil)
+}
+
+// astBuilder 直接从文档模型构建 goldmark AST，省去 Markdown 序列化再解析的过程。
+// 所有文本都是 raw 的 ast.String，渲染时只做 HTML 转义，不再解析 Markdown 语法
+type astBuilder struct {
+	cfg *Config
+}
+
+func buildAST(d *Document, cfg *Config) *ast.Document {
+	b := &astBuilder{cfg: cfg}
+	root := ast.NewDocument()
+	for _, block := range d.Blocks {
+		if n := b.buildBlockNode(block, false); n != nil {
+			root.AppendChild(root, n)
+		}
+	}
+	if len(d.Footnotes) > 0 {
+		list := ext_ast.NewFootnoteList()
+		for _, fn := range d.Footnotes {
+			item := ext_ast.NewFootnote([]byte(strconv.Itoa(fn.Number)))
+			item.Index = fn.Number
+			for _, block := range fn.Blocks {
+				if n := b.buildBlockNode(block, false); n != nil {
+					item.AppendChild(item, n)
+				}
+			}
+			list.AppendChild(list, item)
+			list.Count++
+		}
+		root.AppendChild(root, list)
+	}
+	return root
+}
+
+// buildBlockNode 构建块级节点，tight 为 true 时段落使用 TextBlock (紧凑列表中的列表项)
+func (b *astBuilder) buildBlockNode(block Block, tight bool) ast.Node {
+	switch v := block.(type) {
+	case *Heading:
+		n := ast.NewHeading(v.Level)
+		b.appendInlineNodes(n, v.Inlines)
+		return n
+	case *Paragraph:
+		var n ast.Node = ast.NewParagraph()
+		if tight {
+			n = ast.NewTextBlock()
+		}
+		// 对齐与缩进通过 style 属性交给 renderParagraph 追加
+		if style := paragraphStyle(v); style != "" {
+			n.SetAttributeString("style", []byte(style))
+		}
+		b.appendInlineNodes(n, v.Inlines)
+		return n
+	case *Blockquote:
+		n := ast.NewBlockquote()
+		for _, block := range v.Blocks {
+			if c := b.buildBlockNode(block, false); c != nil {
+				n.AppendChild(n, c)
+			}
+		}
+		return n
+	case *Callout:
+		n := &calloutNode{Variant: v.Kind, Title: v.Title}
+		for _, block := range v.Blocks {
+			if c := b.buildBlockNode(block, false); c != nil {
+				n.AppendChild(n, c)
+			}
+		}
+		return n
+	case *Divider:
+		return ast.NewThematicBreak()
+	case *Figure:
+		n := &figureNode{}
+		b.appendInlineNodes(n, []Inline{v.Image})
+		if len(v.Caption) > 0 || v.Label != "" {
+			caption := &figureCaptionNode{Label: v.Label}
+			b.appendInlineNodes(caption, v.Caption)
+			n.AppendChild(n, caption)
+		}
+		return n
+	case *List:
+		return b.buildListNode(v)
+	case *Table:
+		return b.buildTableNode(v)
+	}
+	return nil
+}
+
+func (b *astBuilder) buildListNode(list *List) ast.Node {
+	marker := byte('*')
+	if list.Ordered {
+		marker = '.'
+	}
+	n := ast.NewList(marker)
+	n.IsTight = true
+	if list.Ordered {
+		n.Start = 1
+	}
+	if list.Checklist {
+		n.SetAttributeString("checklist", true)
+	}
+	for _, item := range list.Items {
+		li := ast.NewListItem(2)
+		for _, block := range item.Blocks {
+			if c := b.buildBlockNode(block, true); c != nil {
+				li.AppendChild(li, c)
+			}
+		}
+		// 与 GFM 任务列表相同，复选框是列表项第一个段落的第一个行内节点
+		if list.Checklist {
+			if first, ok := li.FirstChild().(*ast.TextBlock); ok {
+				first.InsertBefore(first, first.FirstChild(), ext_ast.NewTaskCheckBox(item.Checked))
+			} else {
+				block := ast.NewTextBlock()
+				block.AppendChild(block, ext_ast.NewTaskCheckBox(item.Checked))
+				li.InsertBefore(li, li.FirstChild(), block)
+			}
+		}
+		n.AppendChild(n, li)
+	}
+	return n
+}
+
+func (b *astBuilder) buildTableNode(table *Table) ast.Node {
+	n := ext_ast.NewTable()
+	columns := 0
+	for _, row := range table.Rows {
+		if len(row.Cells) > columns {
+			columns = len(row.Cells)
+		}
+	}
+	n.Alignments = make([]ext_ast.Alignment, columns)
+	for i := range n.Alignments {
+		n.Alignments[i] = ext_ast.AlignNone
+	}
+
+	for i, row := range table.Rows {
+		r := ext_ast.NewTableRow(n.Alignments)
+		for _, cell := range row.Cells {
+			c := ext_ast.NewTableCell()
+			b.appendInlineNodes(c, cell.Inlines)
+			r.AppendChild(r, c)
+		}
+		// 第一行作为表头
+		if i == 0 {
+			n.AppendChild(n, ext_ast.NewTableHeader(r))
+		} else {
+			n.AppendChild(n, r)
+		}
+	}
+	return n
+}
+
+// appendInlineNodes 把行内元素追加到父节点，链接相同的相邻文本共用一个链接节点
+func (b *astBuilder) appendInlineNodes(parent ast.Node, inlines []Inline) {
+	var link *ast.Link
+	for _, in := range mergeTexts(inlines) {
+		t, ok := in.(*Text)
+		if !ok || t.Style.Link == "" {
+			link = nil
+		} else if link == nil || string(link.Destination) != t.Style.Link {
+			link = ast.NewLink()
+			link.Destination = []byte(t.Style.Link)
+			parent.AppendChild(parent, link)
+		}
+
+		var n ast.Node
+		switch v := in.(type) {
+		case *Text:
+			n = b.buildTextNode(v)
+		case *Image:
+			img := ast.NewImage(ast.NewLink())
+			img.Destination = []byte(v.URL)
+			img.AppendChild(img, newRawString(v.Alt))
+			// 显示宽度交给 renderImage 追加到 style 中，接近整页宽度的图片不限制宽度
+			if v.WidthPercent > 0 && v.WidthPercent < 95 {
+				img.SetAttributeString("width", []byte(fmt.Sprintf("%g%%", math.Round(v.WidthPercent))))
+			}
+			// 公式图片按正文字号缩放，行内公式的基线与文字对齐
+			if f := v.Formula; f != nil {
+				img.SetAttributeString("width", []byte(fmt.Sprintf("%.2fem", f.Width)))
+				img.SetAttributeString("height", []byte(fmt.Sprintf("%.2fem", f.Height+f.Depth)))
+				img.SetAttributeString("depth", []byte(fmt.Sprintf("%.2fem", f.Depth)))
+			}
+			n = img
+		case *LineBreak:
+			br := ast.NewTextSegment(text.NewSegment(0, 0))
+			br.SetHardLineBreak(true)
+			n = br
+		case *FootnoteRef:
+			n = ext_ast.NewFootnoteLink(v.Number)
+		}
+		if n == nil {
+			continue
+		}
+		if link != nil {
+			link.AppendChild(link, n)
+		} else {
+			parent.AppendChild(parent, n)
+		}
+	}
+}
+
+// buildTextNode 按文字样式、删除线、粗体、斜体、上下标的顺序由外到内包裹文本
+func (b *astBuilder) buildTextNode(t *Text) ast.Node {
+	var n ast.Node = newRawString(t.Text)
+	wrap := func(w ast.Node) {
+		w.AppendChild(w, n)
+		n = w
+	}
+	switch t.Style.Baseline {
+	case "SUPERSCRIPT":
+		wrap(&styledSpan{Tag: "sup", Style: styleSuperscript})
+	case "SUBSCRIPT":
+		wrap(&styledSpan{Tag: "sub", Style: styleSubscript})
+	}
+	if t.Style.Italic {
+		wrap(ast.NewEmphasis(1))
+	}
+	if t.Style.Bold {
+		wrap(ast.NewEmphasis(2))
+	}
+	if t.Style.Strikethrough {
+		wrap(ext_ast.NewStrikethrough())
+	}
+	var styles []string
+	if style := b.cfg.Colors.inlineStyle(t.Style); style != "" {
+		styles = append(styles, style)
+	}
+	if t.Style.Underline {
+		styles = append(styles, styleUnderline)
+	}
+	if t.Style.SmallCaps {
+		styles = append(styles, styleSmallCaps)
+	}
+	if len(styles) > 0 {
+		wrap(&styledSpan{Style: strings.Join(styles, " ")})
+	}
+	return n
+}
+
+// paragraphStyle 返回段落的对齐与缩进样式
+func paragraphStyle(p *Paragraph) string {
+	var parts []string
+	if p.Align != "" {
+		parts = append(parts, "text-align: "+p.Align+";")
+	}
+	if p.Indent > 0 {
+		parts = append(parts, fmt.Sprintf("padding-left: %gpx;", math.Round(p.Indent/docsBaseFontSize*16)))
+	}
+	if p.FirstLineIndent > 0 {
+		parts = append(parts, fmt.Sprintf("text-indent: %gpx;", math.Round(p.FirstLineIndent/docsBaseFontSize*16)))
+	}
+	return strings.Join(parts, " ")
+}
+
+// newRawString 创建按字面输出的文本节点 (只做 HTML 转义)
+func newRawString(s string) *ast.String {
+	n := ast.NewString([]byte(s))
+	n.SetRaw(true)
+	return n
+}
+
+// renderArticle 把文档模型渲染为微信 HTML 正文 (不含外层容器)
+func renderArticle(d *Document, cfg *Config) ([]byte, error) {
+	root := buildAST(d, cfg)
+	if cfg.Typography {
+		applyTypography(root, nil)
+	}
+	var buf bytes.Buffer
+	if err := newMarkdown().Renderer().Render(&buf, nil, root); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// imageCache 记录已上传图片的地址，以处理后图片内容的 SHA-256 为键，
+// 同一张图片在同一个图床 (Target) 上只上传一次
+type imageCache struct {
+	path    string
+	Entries map[string]*cacheEntry `json:"entries"`
+	dirty   bool
+}
+
+type cacheEntry struct {
+	Size     int                     `json:"size"`
+	Ext      string                  `json:"ext"`
+	Uploads  map[string]cachedUpload `json:"uploads"`
+	LastUsed time.Time               `json:"last_used"`
+}
+
+// cachedUpload 是一次上传的结果，Key 是上传时的对象名，对象名前缀改变后缓存失效
+type cachedUpload struct {
+	Key        string    `json:"key"`
+	URL        string    `json:"url"`
+	UploadedAt time.Time `json:"uploaded_at"`
+}
+
+// loadImageCache 读取缓存文件，文件不存在时返回空缓存
+func loadImageCache(path string) (*imageCache, error) {
+	c := &imageCache{path: path, Entries: map[string]*cacheEntry{}}
+	b, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return c, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("无法读取图片缓存: %v", err)
+	}
+	if err := json.Unmarshal(b, c); err != nil {
+		return nil, fmt.Errorf("无法解析图片缓存 %s: %v", path, err)
+	}
+	if c.Entries == nil {
+		c.Entries = map[string]*cacheEntry{}
+	}
+	return c, nil
+}
+
+// lookup 返回图片在图床上的缓存地址
+func (c *imageCache) lookup(hash, target, key string) (string, bool) {
+	entry, ok := c.Entries[hash]
+	if !ok {
+		return "", false
+	}
+	upload, ok := entry.Uploads[target]
+	if !ok || upload.Key != key {
+		return "", false
+	}
+	entry.LastUsed = time.Now()
+	c.dirty = true
+	return upload.URL, true
+}
+
+// store 记录一次上传的结果
+func (c *imageCache) store(hash string, size int, ext, target, key, url string) {
+	entry, ok := c.Entries[hash]
+	if !ok {
+		entry = &cacheEntry{Size: size, Ext: ext, Uploads: map[string]cachedUpload{}}
+		c.Entries[hash] = entry
+	}
+	now := time.Now()
+	entry.Uploads[target] = cachedUpload{Key: key, URL: url, UploadedAt: now}
+	entry.LastUsed = now
+	c.dirty = true
+}
+
+// prune 删除 before 之后没有用到的记录，返回删除的数量
+func (c *imageCache) prune(before time.Time) int {
+	removed := 0
+	for hash, entry := range c.Entries {
+		if entry.LastUsed.Before(before) {
+			delete(c.Entries, hash)
+			removed++
+		}
+	}
+	if removed > 0 {
+		c.dirty = true
+	}
+	return removed
+}
+
+// save 在缓存有改动时写回文件
+func (c *imageCache) save() error {
+	if !c.dirty {
+		return nil
+	}
+	b, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(c.path, b, 0644); err != nil {
+		return fmt.Errorf("无法写入图片缓存: %v", err)
+	}
+	c.dirty = false
+	return nil
+}
+
+// runCacheCommand 实现 cache 子命令：list 列出缓存，prune 删除长期未使用的记录，clear 清空缓存
+func runCacheCommand(args []string) error {
+	fs := flag.NewFlagSet("cache", flag.ExitOnError)
+	configPath := fs.String("config", "config.json", "配置文件路径 (不存在时使用默认配置)")
+	olderThan := fs.Duration("older-than", 30*24*time.Hour, "prune 时删除超过该时长未使用的记录, 例如: 720h")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "用法: go run . cache [--config <file>] [--older-than <duration>] list|prune|clear")
+		fs.PrintDefaults()
+	}
+	_ = fs.Parse(args)
+	if fs.NArg() != 1 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	cfg, err := loadConfig(*configPath)
+	if err != nil {
+		return err
+	}
+	if cfg.Upload.Cache == "" {
+		return fmt.Errorf("配置文件中的 upload.cache 为空，图片缓存未启用")
+	}
+	cache, err := loadImageCache(cfg.Upload.Cache)
+	if err != nil {
+		return err
+	}
+
+	switch fs.Arg(0) {
+	case "list":
+		printImageCache(cache)
+		return nil
+	case "prune":
+		removed := cache.prune(time.Now().Add(-*olderThan))
+		fmt.Printf("已删除 %d 条超过 %s 未使用的记录，剩余 %d 条\n", removed, *olderThan, len(cache.Entries))
+	case "clear":
+		fmt.Printf("已清空 %d 条记录\n", len(cache.Entries))
+		cache.Entries = map[string]*cacheEntry{}
+		cache.dirty = true
+	default:
+		return fmt.Errorf("未知的 cache 命令: %q", fs.Arg(0))
+	}
+	return cache.save()
+}
+
+func printImageCache(c *imageCache) {
+	hashes := make([]string, 0, len(c.Entries))
+	total := 0
+	for hash, entry := range c.Entries {
+		hashes = append(hashes, hash)
+		total += entry.Size
+	}
+	// 最近用到的排在前面
+	sort.Slice(hashes, func(i, j int) bool {
+		return c.Entries[hashes[i]].LastUsed.After(c.Entries[hashes[j]].LastUsed)
+	})
+	for _, hash := range hashes {
+		entry := c.Entries[hash]
+		fmt.Printf("%s  %8s  %s  最近使用 %s\n", hash[:12], formatBytes(entry.Size),
+			strings.TrimPrefix(entry.Ext, "."), entry.LastUsed.Local().Format("2006-01-02 15:04"))
+		targets := make([]string, 0, len(entry.Uploads))
+		for target := range entry.Uploads {
+			targets = append(targets, target)
+		}
+		sort.Strings(targets)
+		for _, target := range targets {
+			fmt.Printf("    %s  %s\n", target, entry.Uploads[target].URL)
+		}
+	}
+	fmt.Printf("共 %d 张图片，%s (%s)\n", len(c.Entries), formatBytes(total), c.path)
+}
@@ -0,0 +1,226 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+	"unicode/utf16"
+
+	"google.golang.org/api/docs/v1"
+)
+
+// calloutKind 是提示框的类型，与 GitHub 的 > [!NOTE] 等提示相同
+type calloutKind struct {
+	Title string
+	Icon  string
+}
+
+var calloutKinds = map[string]calloutKind{
+	"note":      {Title: "说明", Icon: "ℹ️"},
+	"tip":       {Title: "提示", Icon: "💡"},
+	"important": {Title: "重要", Icon: "📌"},
+	"warning":   {Title: "注意", Icon: "⚠️"},
+	"caution":   {Title: "警告", Icon: "🚫"},
+}
+
+// calloutKeywords 是可以用来开始提示框的关键字 (不区分大小写)
+var calloutKeywords = map[string]string{
+	"note": "note", "info": "note", "说明": "note", "备注": "note",
+	"tip": "tip", "hint": "tip", "提示": "tip",
+	"important": "important", "重要": "important",
+	"warning": "warning", "注意": "warning",
+	"caution": "caution", "danger": "caution", "警告": "caution",
+}
+
+var (
+	// :::tip 可选的标题 ... ::: 包围的段落
+	reCalloutFence = regexp.MustCompile(`^:::\s*(\S+)\s*(.*)$`)
+	// > [!NOTE] 可选的标题，之后以 > 开头的段落
+	reCalloutQuote = regexp.MustCompile(`^>\s*\[!(\w+)\]\s*(.*)$`)
+	// 单格表格或带底色段落的第一行：关键字单独一行，或 "提示：" 后面接正文
+	reCalloutKeyword = regexp.MustCompile(`^\s*([\p{Han}A-Za-z]+)\s*(?:[:：]\s*|\n?$)`)
+	reQuotePrefix    = regexp.MustCompile(`^\s*>[ \t]?`)
+)
+
+// calloutSyntax 是提示框的写法，决定提示框在哪里结束
+type calloutSyntax int
+
+const (
+	// calloutFence 在单独一行的 ::: 处结束
+	calloutFence calloutSyntax = iota
+	// calloutQuote 在第一个不以 > 开头的段落处结束
+	calloutQuote
+	// calloutShaded 在第一个没有底色的段落处结束
+	calloutShaded
+	// calloutTable 是单格表格，表格结束时结束
+	calloutTable
+)
+
+// newCallout 创建提示框，title 为空时使用类型的默认标题
+func newCallout(kind, title string) *Callout {
+	if title = strings.TrimSpace(title); title == "" {
+		title = calloutKinds[kind].Title
+	}
+	return &Callout{Kind: kind, Title: title}
+}
+
+// calloutKeyword 识别第一行开头的关键字，返回提示框类型与关键字部分 (包括冒号)
+func calloutKeyword(text string) (kind, prefix string, ok bool) {
+	m := reCalloutKeyword.FindStringSubmatch(text)
+	if m == nil {
+		return "", "", false
+	}
+	kind, ok = calloutKeywords[strings.ToLower(m[1])]
+	return kind, m[0], ok
+}
+
+// openCallout 开始提示框，之后的内容加入提示框直到 closeCallout
+func (b *docBuilder) openCallout(c *Callout, syntax calloutSyntax) {
+	b.closeCallout()
+	b.closeLists()
+	b.callout, b.calloutSyntax = c, syntax
+	b.outer, b.out.Blocks = b.out.Blocks, nil
+}
+
+// closeCallout 结束当前的提示框，没有内容的提示框被丢弃
+func (b *docBuilder) closeCallout() {
+	if b.callout == nil {
+		return
+	}
+	b.closeLists()
+	c := b.callout
+	c.Blocks = b.out.Blocks
+	b.out.Blocks, b.outer, b.callout = b.outer, nil, nil
+	if len(c.Blocks) > 0 {
+		b.out.Blocks = append(b.out.Blocks, c)
+	}
+}
+
+// interruptCallout 在表格与分节符之前结束 > 与底色写法的提示框，::: 写法的提示框可以包含表格
+func (b *docBuilder) interruptCallout() {
+	if b.calloutSyntax != calloutFence {
+		b.closeCallout()
+	}
+}
+
+// calloutParagraph 处理提示框的开始与结束标记。返回 nil 表示段落是标记本身，
+// 否则返回需要继续处理的段落 (可能去掉了开头的 > 或关键字)
+func (b *docBuilder) calloutParagraph(para *docs.Paragraph, text string) *docs.Paragraph {
+	text = strings.TrimSpace(text)
+	if b.callout != nil && b.calloutSyntax == calloutFence {
+		if text == ":::" {
+			b.closeCallout()
+			return nil
+		}
+		return para
+	}
+	if m := reCalloutFence.FindStringSubmatch(text); m != nil {
+		if kind, ok := calloutKeywords[strings.ToLower(m[1])]; ok {
+			b.openCallout(newCallout(kind, m[2]), calloutFence)
+			return nil
+		}
+	}
+	if m := reCalloutQuote.FindStringSubmatch(text); m != nil {
+		if kind, ok := calloutKeywords[strings.ToLower(m[1])]; ok {
+			b.openCallout(newCallout(kind, m[2]), calloutQuote)
+			return nil
+		}
+	}
+	if b.callout != nil && b.calloutSyntax == calloutQuote {
+		if prefix := reQuotePrefix.FindString(paragraphText(para)); prefix != "" {
+			return trimParagraphPrefix(para, prefix)
+		}
+		b.closeCallout()
+	}
+
+	shaded := isShaded(para)
+	if b.callout != nil && b.calloutSyntax == calloutShaded {
+		if shaded {
+			return para
+		}
+		b.closeCallout()
+	}
+	if shaded && para.ParagraphStyle.NamedStyleType == "NORMAL_TEXT" && text != "" {
+		kind, prefix, ok := calloutKeyword(paragraphText(para))
+		if !ok {
+			kind, prefix = "note", ""
+		}
+		b.openCallout(newCallout(kind, ""), calloutShaded)
+		return trimParagraphPrefix(para, prefix)
+	}
+	return para
+}
+
+// calloutTableContent 判断表格是否为提示框：只有一个单元格，且第一行是提示框关键字。
+// 是提示框时把单元格的内容作为提示框的内容
+func (b *docBuilder) calloutTableContent(table *docs.Table) bool {
+	if b.callout != nil || len(table.TableRows) != 1 || len(table.TableRows[0].TableCells) != 1 {
+		return false
+	}
+	content := table.TableRows[0].TableCells[0].Content
+	if len(content) == 0 || content[0].Paragraph == nil {
+		return false
+	}
+	kind, prefix, ok := calloutKeyword(paragraphText(content[0].Paragraph))
+	if !ok {
+		return false
+	}
+	b.openCallout(newCallout(kind, ""), calloutTable)
+	for i, elem := range content {
+		switch {
+		case elem.Paragraph != nil:
+			para := elem.Paragraph
+			if i == 0 {
+				para = trimParagraphPrefix(para, prefix)
+			}
+			b.addParagraph(para)
+			b.addPositionedImages(para)
+		case elem.Table != nil:
+			b.closeLists()
+			if t := b.buildTable(elem.Table); t != nil {
+				b.out.Blocks = append(b.out.Blocks, t)
+			}
+		}
+	}
+	b.closeCallout()
+	return true
+}
+
+// isShaded 判断段落是否设置了底色 (格式 → 段落样式 → 边框和底纹)
+func isShaded(para *docs.Paragraph) bool {
+	style := para.ParagraphStyle
+	return style != nil && style.Shading != nil && style.Shading.BackgroundColor != nil &&
+		style.Shading.BackgroundColor.Color != nil
+}
+
+// trimParagraphPrefix 返回去掉开头 prefix 后的段落副本，下标随之后移以便按命名范围隐藏内容。
+// 开头不是文本时无法去掉，原样返回
+func trimParagraphPrefix(para *docs.Paragraph, prefix string) *docs.Paragraph {
+	n := int64(len(utf16.Encode([]rune(prefix))))
+	if n == 0 {
+		return para
+	}
+	trimmed := *para
+	trimmed.Elements = nil
+	for i, elem := range para.Elements {
+		if n == 0 {
+			trimmed.Elements = append(trimmed.Elements, para.Elements[i:]...)
+			break
+		}
+		if elem.TextRun == nil {
+			return para
+		}
+		units := utf16.Encode([]rune(elem.TextRun.Content))
+		if int64(len(units)) <= n {
+			n -= int64(len(units))
+			continue
+		}
+		run := *elem.TextRun
+		run.Content = string(utf16.Decode(units[n:]))
+		e := *elem
+		e.TextRun = &run
+		e.StartIndex += n
+		trimmed.Elements = append(trimmed.Elements, &e)
+		n = 0
+	}
+	return &trimmed
+}
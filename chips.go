@@ -0,0 +1,122 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	_ "time/tzdata" // Windows 等没有时区数据库的系统也能按芯片的时区显示日期
+
+	"google.golang.org/api/docs/v1"
+)
+
+// 日期智能芯片的输出格式，chips.locale 为空时沿用文档中显示的文字
+var dateLocales = map[string]bool{
+	"":      true,
+	"zh-CN": true,
+	"en-US": true,
+	"iso":   true,
+}
+
+// richLinkText 把富链接 (Drive 文件、YouTube 视频等) 转换为以标题为文字的链接
+func richLinkText(link *docs.RichLink) *Text {
+	props := link.RichLinkProperties
+	if props == nil || props.Uri == "" {
+		return nil
+	}
+	style := newTextStyle(link.TextStyle)
+	style.Link = props.Uri
+	title := strings.TrimSpace(props.Title)
+	if title == "" {
+		title = props.Uri
+	}
+	return &Text{Text: title, Style: style}
+}
+
+// personText 把 @ 提及的人转换为显示名，showEmail 为 true 时在名字后面附上邮箱
+func personText(person *docs.Person, showEmail bool) *Text {
+	props := person.PersonProperties
+	if props == nil {
+		return nil
+	}
+	name, email := strings.TrimSpace(props.Name), strings.TrimSpace(props.Email)
+	text := name
+	switch {
+	case name == "" && !showEmail:
+		// 只有邮箱时取 @ 前面的部分
+		text, _, _ = strings.Cut(email, "@")
+	case name == "":
+		text = email
+	case showEmail && email != "":
+		text = fmt.Sprintf("%s (%s)", name, email)
+	}
+	if text == "" {
+		return nil
+	}
+	return &Text{Text: text, Style: newTextStyle(person.TextStyle)}
+}
+
+// dateText 按 locale 格式化日期智能芯片，无法解析时间时使用文档中显示的文字
+func dateText(date *docs.DateElement, locale string) *Text {
+	props := date.DateElementProperties
+	if props == nil {
+		return nil
+	}
+	text := props.DisplayText
+	if locale != "" {
+		if formatted, err := formatDate(props, locale); err == nil {
+			text = formatted
+		} else if text == "" {
+			fmt.Printf("无法格式化日期 %q: %v\n", props.Timestamp, err)
+		}
+	}
+	if strings.TrimSpace(text) == "" {
+		return nil
+	}
+	return &Text{Text: text, Style: newTextStyle(date.TextStyle)}
+}
+
+// formatDate 按芯片的日期与时间格式设置输出本地化的日期，时区取芯片设置的时区
+func formatDate(props *docs.DateElementProperties, locale string) (string, error) {
+	t, err := time.Parse(time.RFC3339Nano, props.Timestamp)
+	if err != nil {
+		return "", err
+	}
+	if props.TimeZoneId != "" {
+		if loc, err := time.LoadLocation(props.TimeZoneId); err == nil {
+			t = t.In(loc)
+		}
+	}
+	withYear := props.DateFormat != "DATE_FORMAT_MONTH_DAY_ABBREVIATED" && props.DateFormat != "DATE_FORMAT_MONTH_DAY_FULL"
+	if props.DateFormat == "DATE_FORMAT_ISO8601" {
+		locale = "iso"
+	}
+
+	var layout, timeLayout string
+	switch locale {
+	case "zh-CN":
+		layout, timeLayout = "1月2日", "15:04"
+		if withYear {
+			layout = "2006年1月2日"
+		}
+	case "en-US":
+		layout, timeLayout = "Jan 2", "3:04 PM"
+		if props.DateFormat == "DATE_FORMAT_MONTH_DAY_FULL" {
+			layout = "January 2"
+		}
+		if withYear {
+			layout += ", 2006"
+		}
+	default:
+		layout, timeLayout = "2006-01-02", "15:04"
+		if !withYear {
+			layout = "01-02"
+		}
+	}
+	switch props.TimeFormat {
+	case "TIME_FORMAT_HOUR_MINUTE":
+		layout += " " + timeLayout
+	case "TIME_FORMAT_HOUR_MINUTE_TIMEZONE":
+		layout += " " + timeLayout + " MST"
+	}
+	return t.Format(layout), nil
+}
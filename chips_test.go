@@ -0,0 +1,31 @@
+package main
+
+import (
+	"testing"
+
+	"google.golang.org/api/docs/v1"
+)
+
+func TestPersonText(t *testing.T) {
+	tests := []struct {
+		name, email string
+		showEmail   bool
+		want        string
+	}{
+		{"张三", "zhangsan@example.com", false, "张三"},
+		{"张三", "zhangsan@example.com", true, "张三 (zhangsan@example.com)"},
+		{"", "zhangsan@example.com", false, "zhangsan"},
+		{"", "zhangsan@example.com", true, "zhangsan@example.com"},
+		{"张三", "", true, "张三"},
+	}
+	for _, tt := range tests {
+		person := &docs.Person{PersonProperties: &docs.PersonProperties{Name: tt.name, Email: tt.email}}
+		got := personText(person, tt.showEmail)
+		if got == nil || got.Text != tt.want {
+			t.Errorf("personText(%q, %q, %v) = %+v, 期望 %q", tt.name, tt.email, tt.showEmail, got, tt.want)
+		}
+	}
+	if got := personText(&docs.Person{PersonProperties: &docs.PersonProperties{}}, true); got != nil {
+		t.Errorf("没有名字和邮箱时应返回 nil, 得到 %+v", got)
+	}
+}
@@ -0,0 +1,273 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// Config 是 config.json 中的可选配置，命令行参数会覆盖其中的同名设置
+type Config struct {
+	// Typography 启用中英文排版规范化，等同于 --typography
+	Typography bool `json:"typography"`
+	// Suggestions 决定如何处理未处理的建议修改："accept"、"reject" (默认) 或 "fail"，等同于 --suggestions
+	Suggestions string `json:"suggestions"`
+	// Target 是输出目标："wechat" (默认) 或 "web"，决定命名范围 wechat-only 与 web-only 是否保留，等同于 --target
+	Target string `json:"target"`
+	// Colors 控制文字颜色、背景高亮与字号的输出方式
+	Colors ColorConfig `json:"colors"`
+	// IndentAsBlockquote 为 true 时，没有项目符号的缩进段落转换为引用块，否则保留缩进
+	IndentAsBlockquote bool `json:"indent_as_blockquote"`
+	// SplitAtPageBreaks 为 true 时在分页符处把文档分成多篇文章，否则分页符与水平线一样显示为分隔线
+	SplitAtPageBreaks bool `json:"split_at_page_breaks"`
+	// Filters 决定文档中哪些内容需要发布
+	Filters FilterConfig `json:"filters"`
+	// Chips 控制智能芯片 (人员、日期) 的输出
+	Chips ChipConfig `json:"chips"`
+	// Math 控制 LaTeX 公式的渲染
+	Math MathConfig `json:"math"`
+	// Figures 控制图片说明的编号
+	Figures FigureConfig `json:"figures"`
+	// Images 控制下载图片的压缩与缩放
+	Images ImageConfig `json:"images"`
+	// Upload 选择上传图片的图床，未配置时图片地址为占位符
+	Upload UploadConfig `json:"upload"`
+	// WeChat 是公众号接口的凭证
+	WeChat WeChatConfig `json:"wechat"`
+	// Cover 控制 cover 命令生成的封面
+	Cover CoverConfig `json:"cover"`
+}
+
+// WeChatConfig 是公众号的开发者凭证，为空时从 WECHAT_APPID 与 WECHAT_APPSECRET 读取
+type WeChatConfig struct {
+	AppID     string `json:"app_id"`
+	AppSecret string `json:"app_secret"`
+}
+
+// CoverConfig 控制封面的背景与文字
+type CoverConfig struct {
+	// Background 是封面模板背景图片，为空时使用主题色渐变
+	Background string `json:"background"`
+	// Font 是包含中文字形的 TTF/OTF/TTC 字体文件，为空时使用系统中的中文字体
+	Font string `json:"font"`
+	// Subtitle 是默认副标题，文档中的副标题与 --subtitle 优先
+	Subtitle      string `json:"subtitle"`
+	TitleColor    string `json:"title_color"`
+	SubtitleColor string `json:"subtitle_color"`
+}
+
+// UploadConfig 选择图床并配置其参数，密钥为空时从对应的环境变量读取
+type UploadConfig struct {
+	// Provider 为 "local"、"s3"、"cos"、"oss" 或 "wechat"，为空时不上传
+	Provider string `json:"provider"`
+	// Prefix 是对象名前缀 (如 "wechat/")，对象名为前缀加图片内容的哈希
+	Prefix string `json:"prefix"`
+	// Cache 是已上传图片的缓存文件，默认为 image-cache.json，为空时不使用缓存
+	Cache string            `json:"cache"`
+	Local LocalUploadConfig `json:"local"`
+	S3    S3Config          `json:"s3"`
+	COS   COSConfig         `json:"cos"`
+	OSS   OSSConfig         `json:"oss"`
+}
+
+// LocalUploadConfig 把图片复制到本地目录，BaseURL 是该目录对外的访问地址
+type LocalUploadConfig struct {
+	Dir     string `json:"dir"`
+	BaseURL string `json:"base_url"`
+}
+
+// S3Config 是 S3 兼容存储的配置，密钥默认读取 AWS_ACCESS_KEY_ID 与 AWS_SECRET_ACCESS_KEY
+type S3Config struct {
+	// Endpoint 如 "https://s3.us-east-1.amazonaws.com" 或 "http://127.0.0.1:9000"，为空时按 Region 使用 AWS S3
+	Endpoint  string `json:"endpoint"`
+	Region    string `json:"region"`
+	Bucket    string `json:"bucket"`
+	AccessKey string `json:"access_key"`
+	SecretKey string `json:"secret_key"`
+	// PathStyle 为 true 时使用 endpoint/bucket/key 形式的地址 (MinIO 等自建存储通常需要)
+	PathStyle bool `json:"path_style"`
+	// BaseURL 是可选的 CDN 地址，设置后图片地址为 BaseURL 加对象名
+	BaseURL string `json:"base_url"`
+}
+
+// COSConfig 是腾讯云 COS 的配置，密钥默认读取 COS_SECRET_ID 与 COS_SECRET_KEY
+type COSConfig struct {
+	// Bucket 包含 APPID，如 "images-1250000000"
+	Bucket    string `json:"bucket"`
+	Region    string `json:"region"`
+	SecretID  string `json:"secret_id"`
+	SecretKey string `json:"secret_key"`
+	BaseURL   string `json:"base_url"`
+}
+
+// OSSConfig 是阿里云 OSS 的配置，密钥默认读取 OSS_ACCESS_KEY_ID 与 OSS_ACCESS_KEY_SECRET
+type OSSConfig struct {
+	// Endpoint 如 "oss-cn-hangzhou.aliyuncs.com"
+	Endpoint        string `json:"endpoint"`
+	Bucket          string `json:"bucket"`
+	AccessKeyID     string `json:"access_key_id"`
+	AccessKeySecret string `json:"access_key_secret"`
+	BaseURL         string `json:"base_url"`
+}
+
+// ImageConfig 控制图片的优化，默认值符合微信 uploadimg 接口的限制 (jpg/png，不超过 1MB)
+type ImageConfig struct {
+	// MaxWidth 是图片的最大宽度 (像素)，更宽的图片会等比缩小
+	MaxWidth int `json:"max_width"`
+	// MaxBytes 是单张图片的最大字节数，超出时降低 JPEG 质量或继续缩小
+	MaxBytes int `json:"max_bytes"`
+	// Quality 是 JPEG 的初始编码质量 (1-100)
+	Quality int `json:"quality"`
+	// Watermark 是可选的水印，Text 与 Logo 都为空时不加水印
+	Watermark WatermarkConfig `json:"watermark"`
+}
+
+// WatermarkConfig 控制图片水印，Logo 优先于 Text
+type WatermarkConfig struct {
+	Text string `json:"text"`
+	// Logo 是 PNG/JPEG 格式的 Logo 图片路径
+	Logo string `json:"logo"`
+	// Font 是文字水印使用的 TTF/OTF 字体，默认字体不支持中文
+	Font string `json:"font"`
+	// Color 是文字颜色，默认为白色
+	Color string `json:"color"`
+	// Position 为 "bottom-right" (默认)、"bottom-left"、"top-right"、"top-left" 或 "center"
+	Position string `json:"position"`
+	// Opacity 是水印的不透明度 (0-1)
+	Opacity float64 `json:"opacity"`
+	// Scale 是水印宽度占图片宽度的比例
+	Scale float64 `json:"scale"`
+	// MinWidth 是加水印的最小图片宽度 (像素)，更小的图片 (如图标) 不加水印
+	MinWidth int `json:"min_width"`
+}
+
+// FilterConfig 是内容过滤规则。标题按文字匹配 (忽略大小写与首尾空格)
+type FilterConfig struct {
+	// StopAt 遇到文字与之相同的段落时停止转换，默认为参考文献章节。
+	// StopAt 与 SkipStyles 在配置文件中设置后替换默认列表，而不是追加
+	StopAt []string `json:"stop_at"`
+	// SkipSections 跳过这些章节 (包括章节标题)
+	SkipSections []SectionRange `json:"skip_sections"`
+	// IncludeSections 不为空时只发布这些章节中的内容
+	IncludeSections []SectionRange `json:"include_sections"`
+	// SkipStyles 跳过这些命名样式的段落 (如 "TITLE"、"SUBTITLE"、"HEADING_4")，默认为 ["TITLE"]，即跳过文档标题
+	SkipStyles []string `json:"skip_styles"`
+}
+
+// SectionRange 是从标题 From 开始的章节，到标题 To 之前结束；To 为空时到下一个同级或更高级的标题结束
+type SectionRange struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// ChipConfig 控制智能芯片的转换方式，富链接总是转换为以标题为文字的链接
+type ChipConfig struct {
+	// ShowEmail 为 true 时在 @ 提及的人的名字后面附上邮箱，默认只显示名字，避免公开作者同事的邮箱
+	ShowEmail bool `json:"show_email"`
+	// Locale 是日期的格式："zh-CN" (默认)、"en-US"、"iso" (2006-01-02)，为空时沿用文档中显示的文字
+	Locale string `json:"locale"`
+}
+
+// MathConfig 控制 LaTeX 公式：文字中的 $...$ 与 $$...$$ 渲染为公式图片
+type MathConfig struct {
+	// Enabled 为 true 时识别公式。默认关闭，$ 原样输出，避免把金额、$PATH 这样的文字当作公式
+	Enabled bool `json:"enabled"`
+}
+
+// FigureConfig 控制图片说明的自动编号
+type FigureConfig struct {
+	// Label 是编号前缀，默认为 "图"
+	Label string `json:"label"`
+	// NoNumbering 为 true 时不生成 "图 1" 这样的编号
+	NoNumbering bool `json:"no_numbering"`
+}
+
+// ColorConfig 控制 Google Docs 中的文字颜色、高亮与字号如何输出到微信
+type ColorConfig struct {
+	// Mode 为 "palette" (默认) 时颜色映射到主题色板中最接近的颜色；
+	// 为 "allowlist" 时只输出 Allowlist 中的颜色；为 "none" 时忽略所有颜色
+	Mode string `json:"mode"`
+	// Allowlist 中的颜色 (如 "#ff0000") 在 palette 与 allowlist 模式下原样输出
+	Allowlist []string `json:"allowlist"`
+	// IgnoreFontSize 为 true 时不输出字号
+	IgnoreFontSize bool `json:"ignore_font_size"`
+}
+
+func defaultConfig() *Config {
+	return &Config{
+		Suggestions: "reject",
+		Target:      "wechat",
+		Colors:      ColorConfig{Mode: "palette"},
+		Filters: FilterConfig{
+			StopAt:     []string{"参考文献", "引用的文献", "References"},
+			SkipStyles: []string{"TITLE"},
+		},
+		Chips:   ChipConfig{Locale: "zh-CN"},
+		Figures: FigureConfig{Label: "图"},
+		Images: ImageConfig{
+			MaxWidth: 1080,
+			MaxBytes: 1 << 20,
+			Quality:  85,
+			Watermark: WatermarkConfig{
+				Color:    "#ffffff",
+				Position: "bottom-right",
+				Opacity:  0.6,
+				Scale:    0.2,
+				MinWidth: 300,
+			},
+		},
+		Upload: UploadConfig{Cache: "image-cache.json"},
+		Cover:  CoverConfig{TitleColor: "#ffffff", SubtitleColor: colorPrimaryLight},
+	}
+}
+
+// loadConfig 读取配置文件，文件不存在时使用默认配置
+func loadConfig(path string) (*Config, error) {
+	cfg := defaultConfig()
+	b, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return cfg, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("无法读取配置文件: %v", err)
+	}
+	if err := json.Unmarshal(b, cfg); err != nil {
+		return nil, fmt.Errorf("无法解析配置文件 %s: %v", path, err)
+	}
+	if cfg.Suggestions == "" {
+		cfg.Suggestions = "reject"
+	}
+	if _, ok := suggestionsViewModes[cfg.Suggestions]; !ok {
+		return nil, fmt.Errorf("未知的建议处理方式: %q", cfg.Suggestions)
+	}
+	if !outputTargets[cfg.Target] {
+		return nil, fmt.Errorf("未知的输出目标: %q", cfg.Target)
+	}
+	if !dateLocales[cfg.Chips.Locale] {
+		return nil, fmt.Errorf("未知的日期格式: %q", cfg.Chips.Locale)
+	}
+	switch cfg.Colors.Mode {
+	case "palette", "allowlist", "none":
+	case "":
+		cfg.Colors.Mode = "palette"
+	default:
+		return nil, fmt.Errorf("未知的颜色模式: %q", cfg.Colors.Mode)
+	}
+	if cfg.Images.MaxWidth <= 0 || cfg.Images.MaxBytes <= 0 {
+		return nil, fmt.Errorf("图片的 max_width 与 max_bytes 必须大于 0")
+	}
+	if cfg.Images.Quality < 1 || cfg.Images.Quality > 100 {
+		return nil, fmt.Errorf("图片的 quality 必须在 1-100 之间: %d", cfg.Images.Quality)
+	}
+	wm := cfg.Images.Watermark
+	switch wm.Position {
+	case "bottom-right", "bottom-left", "top-right", "top-left", "center":
+	default:
+		return nil, fmt.Errorf("未知的水印位置: %q", wm.Position)
+	}
+	if wm.Opacity <= 0 || wm.Opacity > 1 || wm.Scale <= 0 || wm.Scale > 1 {
+		return nil, fmt.Errorf("水印的 opacity 与 scale 必须在 0-1 之间")
+	}
+	return cfg, nil
+}
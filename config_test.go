@@ -0,0 +1,64 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func writeTestConfig(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadConfigSkipStyles(t *testing.T) {
+	tests := []struct {
+		name   string
+		config string
+		want   []string
+	}{
+		{"默认跳过标题", `{}`, []string{"TITLE"}},
+		{"配置替换默认列表", `{"filters": {"skip_styles": ["SUBTITLE"]}}`, []string{"SUBTITLE"}},
+		{"空列表不跳过任何样式", `{"filters": {"skip_styles": []}}`, []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := loadConfig(writeTestConfig(t, tt.config))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(cfg.Filters.SkipStyles, tt.want) {
+				t.Errorf("skip_styles = %q, 期望 %q", cfg.Filters.SkipStyles, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadConfigSuggestions(t *testing.T) {
+	tests := []struct {
+		config string
+		want   string
+	}{
+		{`{}`, "reject"},
+		{`{"suggestions": ""}`, "reject"},
+		{`{"suggestions": "fail"}`, "fail"},
+	}
+	for _, tt := range tests {
+		cfg, err := loadConfig(writeTestConfig(t, tt.config))
+		if err != nil {
+			t.Errorf("loadConfig(%s) 出错: %v", tt.config, err)
+			continue
+		}
+		if cfg.Suggestions != tt.want {
+			t.Errorf("loadConfig(%s) 的 suggestions = %q, 期望 %q", tt.config, cfg.Suggestions, tt.want)
+		}
+	}
+	if _, err := loadConfig(writeTestConfig(t, `{"suggestions": "ignore"}`)); err == nil {
+		t.Error("未知的建议处理方式应返回错误")
+	}
+}
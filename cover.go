@@ -0,0 +1,417 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+
+	"golang.org/x/image/draw"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/font/sfnt"
+	"golang.org/x/image/math/fixed"
+)
+
+// coverSize 是一种封面尺寸：900×383 是公众号文章封面 (2.35:1)，500×500 用于分享卡片
+type coverSize struct {
+	Name          string
+	Width, Height int
+}
+
+var coverSizes = []coverSize{
+	{"cover.jpg", 900, 383},
+	{"cover-square.jpg", 500, 500},
+}
+
+// 不能出现在行首的标点，换行时跟随前一个字
+const noLineStart = "，。、；：？！）》」』”’,.;:?!)]}…%"
+
+// coverFont 是封面文字使用的 TTF/OTF 字体
+type coverFont struct {
+	ttf *opentype.Font
+}
+
+// systemCJKFonts 是未设置 cover.font 时依次尝试的系统中文字体 (macOS、Windows、常见 Linux 发行版)。
+// 完整的中文字体有十几 MB，不随程序内置
+var systemCJKFonts = []string{
+	"/System/Library/Fonts/PingFang.ttc",
+	"/System/Library/Fonts/STHeiti Medium.ttc",
+	"/System/Library/Fonts/Hiragino Sans GB.ttc",
+	`C:\Windows\Fonts\msyhbd.ttc`,
+	`C:\Windows\Fonts\msyh.ttc`,
+	`C:\Windows\Fonts\simhei.ttf`,
+	"/usr/share/fonts/opentype/noto/NotoSansCJK-Bold.ttc",
+	"/usr/share/fonts/noto-cjk/NotoSansCJK-Bold.ttc",
+	"/usr/share/fonts/google-noto-cjk/NotoSansCJK-Bold.ttc",
+	"/usr/share/fonts/opentype/noto/NotoSansCJK-Regular.ttc",
+	"/usr/share/fonts/noto-cjk/NotoSansCJK-Regular.ttc",
+	"/usr/share/fonts/truetype/wqy/wqy-microhei.ttc",
+	"/usr/share/fonts/wqy-microhei/wqy-microhei.ttc",
+}
+
+// loadCoverFont 读取封面字体。path 为空时使用系统中的中文字体，都找不到时要求指定字体文件
+func loadCoverFont(path string) (*coverFont, error) {
+	if path != "" {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("无法读取封面字体: %v", err)
+		}
+		f, err := parseCoverFont(b)
+		if err != nil {
+			return nil, fmt.Errorf("无法使用封面字体 %s: %v", path, err)
+		}
+		return &coverFont{ttf: f}, nil
+	}
+	for _, p := range systemCJKFonts {
+		b, err := os.ReadFile(p)
+		if err != nil {
+			continue
+		}
+		if f, err := parseCoverFont(b); err == nil {
+			fmt.Printf("使用系统字体生成封面: %s\n", p)
+			return &coverFont{ttf: f}, nil
+		}
+	}
+	return nil, fmt.Errorf("没有找到支持中文的系统字体，请在配置文件中设置 cover.font 或使用 --font 指定 TTF/OTF 字体 (如 NotoSansSC-Bold.ttf)")
+}
+
+// parseCoverFont 解析 TTF/OTF 字体或字体集 (TTC)，字体集优先使用简体中文 (SC/GB) 字体。
+// 不包含中文字形的字体会画出方框，直接报错
+func parseCoverFont(b []byte) (*opentype.Font, error) {
+	var fonts []*opentype.Font
+	if f, err := opentype.Parse(b); err == nil {
+		fonts = append(fonts, f)
+	} else {
+		c, cerr := opentype.ParseCollection(b)
+		if cerr != nil {
+			return nil, err
+		}
+		for i := 0; i < c.NumFonts(); i++ {
+			if f, err := c.Font(i); err == nil {
+				fonts = append(fonts, f)
+			}
+		}
+	}
+	var buf sfnt.Buffer
+	var found *opentype.Font
+	for _, f := range fonts {
+		if i, err := f.GlyphIndex(&buf, '中'); err != nil || i == 0 {
+			continue
+		}
+		name, _ := f.Name(&buf, sfnt.NameIDFamily)
+		if strings.Contains(name, " SC") || strings.Contains(name, "GB") {
+			return f, nil
+		}
+		if found == nil {
+			found = f
+		}
+	}
+	if found == nil {
+		return nil, fmt.Errorf("字体不包含中文字形")
+	}
+	return found, nil
+}
+
+// face 返回 px 像素大小的字体
+func (f *coverFont) face(px float64) (font.Face, error) {
+	return opentype.NewFace(f.ttf, &opentype.FaceOptions{Size: px, DPI: 72, Hinting: font.HintingFull})
+}
+
+// textBlock 是排好版的一段文字
+type textBlock struct {
+	face  font.Face
+	lines []string
+	color color.Color
+}
+
+func (t *textBlock) lineHeight() int {
+	m := t.face.Metrics()
+	return (m.Ascent + m.Descent).Ceil() * 5 / 4
+}
+
+func (t *textBlock) height() int {
+	return t.lineHeight() * len(t.lines)
+}
+
+// layoutText 从 maxPx 开始逐步缩小字号，直到文字在 width 内不超过 maxLines 行且总高度不超过 maxHeight
+func (f *coverFont) layoutText(text string, width, maxHeight int, maxPx, minPx float64, maxLines int, c color.Color) (*textBlock, error) {
+	var block *textBlock
+	for px := maxPx; px >= minPx; px -= 2 {
+		face, err := f.face(px)
+		if err != nil {
+			return nil, err
+		}
+		block = &textBlock{face: face, lines: wrapText(face, text, width), color: c}
+		if len(block.lines) <= maxLines && block.height() <= maxHeight {
+			break
+		}
+	}
+	if len(block.lines) > maxLines {
+		block.lines = block.lines[:maxLines]
+		block.lines[maxLines-1] = strings.TrimRight(block.lines[maxLines-1], " ") + "…"
+	}
+	return block, nil
+}
+
+// wrapText 按宽度折行：中文按字折行，英文单词不拆开 (超过整行宽度的单词除外)，行首避开标点
+func wrapText(face font.Face, text string, width int) []string {
+	var tokens []string
+	for _, r := range strings.Join(strings.Fields(text), " ") {
+		n := len(tokens)
+		switch {
+		case n > 0 && strings.ContainsRune(noLineStart, r):
+			tokens[n-1] += string(r)
+		case n > 0 && r != ' ' && !isWideRune(r) && isWordToken(tokens[n-1]):
+			tokens[n-1] += string(r)
+		default:
+			tokens = append(tokens, string(r))
+		}
+	}
+
+	limit := fixed.I(width)
+	var lines []string
+	var line string
+	for _, tok := range tokens {
+		if line == "" && tok == " " {
+			continue
+		}
+		if font.MeasureString(face, line+tok) <= limit {
+			line += tok
+			continue
+		}
+		if line != "" {
+			lines = append(lines, strings.TrimRight(line, " "))
+			line = ""
+		}
+		if tok == " " {
+			continue
+		}
+		// 单个词超过整行宽度时按字符拆开
+		for _, r := range tok {
+			if line != "" && font.MeasureString(face, line+string(r)) > limit {
+				lines = append(lines, line)
+				line = ""
+			}
+			line += string(r)
+		}
+	}
+	if line = strings.TrimRight(line, " "); line != "" {
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+func isWideRune(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul) || (r >= 0x3000 && r <= 0x303f) || (r >= 0xff00 && r <= 0xffef)
+}
+
+func isWordToken(tok string) bool {
+	for _, r := range tok {
+		if r == ' ' || isWideRune(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// draw 把文字逐行居中绘制到 dst，top 为第一行的顶部
+func (t *textBlock) draw(dst *image.RGBA, top int) {
+	ascent := t.face.Metrics().Ascent
+	d := &font.Drawer{Dst: dst, Src: image.NewUniform(t.color), Face: t.face}
+	for i, line := range t.lines {
+		w := d.MeasureString(line)
+		d.Dot = fixed.Point26_6{
+			X: (fixed.I(dst.Bounds().Dx()) - w) / 2,
+			Y: fixed.I(top+i*t.lineHeight()) + ascent,
+		}
+		d.DrawString(line)
+	}
+}
+
+// renderCover 生成一张封面：背景图 (或主题色渐变) 上居中排列标题与副标题
+func renderCover(cfg CoverConfig, f *coverFont, background image.Image, title, subtitle string, width, height int) (image.Image, error) {
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	if background != nil {
+		fillImage(dst, background)
+	} else {
+		drawGradient(dst, colorPrimary, "#0a3d91")
+	}
+
+	margin := width / 12
+	textWidth := width - 2*margin
+	base := float64(min(width, height*2))
+	// 方形封面纵向空间充足，标题最多 4 行
+	maxLines := 3
+	if height*5 >= width*4 {
+		maxLines = 4
+	}
+	titleColor, subtitleColor := hexColor(cfg.TitleColor, color.White), hexColor(cfg.SubtitleColor, color.White)
+	titleBlock, err := f.layoutText(title, textWidth, height*3/5, base/13, base/30, maxLines, titleColor)
+	if err != nil {
+		return nil, err
+	}
+	blocks := []*textBlock{titleBlock}
+	if subtitle != "" {
+		subtitleBlock, err := f.layoutText(subtitle, textWidth, height/5, base/26, base/45, 2, subtitleColor)
+		if err != nil {
+			return nil, err
+		}
+		blocks = append(blocks, subtitleBlock)
+	}
+
+	gap := height / 20
+	total := gap * (len(blocks) - 1)
+	for _, b := range blocks {
+		total += b.height()
+	}
+	top := (height - total) / 2
+	for _, b := range blocks {
+		b.draw(dst, top)
+		top += b.height() + gap
+	}
+	return dst, nil
+}
+
+// fillImage 把背景图等比缩放并居中裁剪，铺满整个封面
+func fillImage(dst *image.RGBA, src image.Image) {
+	sb, db := src.Bounds(), dst.Bounds()
+	scale := math.Max(float64(db.Dx())/float64(sb.Dx()), float64(db.Dy())/float64(sb.Dy()))
+	w, h := int(math.Round(float64(db.Dx())/scale)), int(math.Round(float64(db.Dy())/scale))
+	crop := image.Rect(0, 0, w, h).Add(sb.Min).Add(image.Pt((sb.Dx()-w)/2, (sb.Dy()-h)/2))
+	draw.CatmullRom.Scale(dst, db, src, crop, draw.Src, nil)
+}
+
+// drawGradient 绘制从左上到右下的线性渐变
+func drawGradient(dst *image.RGBA, from, to string) {
+	r1, g1, b1, _ := parseHexColor(from)
+	r2, g2, b2, _ := parseHexColor(to)
+	b := dst.Bounds()
+	span := float64(b.Dx() + b.Dy())
+	lerp := func(a, b int, t float64) uint8 { return uint8(math.Round(float64(a) + float64(b-a)*t)) }
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			t := float64(x+y) / span
+			dst.SetRGBA(x, y, color.RGBA{lerp(r1, r2, t), lerp(g1, g2, t), lerp(b1, b2, t), 255})
+		}
+	}
+}
+
+func hexColor(hex string, fallback color.Color) color.Color {
+	if r, g, b, ok := parseHexColor(hex); ok {
+		return color.RGBA{uint8(r), uint8(g), uint8(b), 255}
+	}
+	return fallback
+}
+
+// runCoverCommand 实现 cover 子命令：根据文档标题生成封面，可选上传为公众号永久素材以获得 thumb_media_id
+func runCoverCommand(args []string) error {
+	fs := flag.NewFlagSet("cover", flag.ExitOnError)
+	configPath := fs.String("config", "config.json", "配置文件路径 (不存在时使用默认配置)")
+	proxyAddr := fs.String("proxy", "", "SOCKS5 代理地址和端口, 例如: 127.0.0.1:1080")
+	title := fs.String("title", "", "封面标题，为空时使用文档标题")
+	tab := fs.String("tab", "", "使用该标签页 (标题或 ID) 中的标题，默认为第一个标签页")
+	subtitle := fs.String("subtitle", "", "封面副标题，为空时使用文档中的副标题")
+	outDir := fs.String("out", ".", "封面输出目录")
+	fontPath := fs.String("font", "", "封面字体 (TTF/OTF/TTC)，覆盖配置文件中的 cover.font")
+	upload := fs.Bool("upload", false, "把 900×383 封面上传为公众号永久素材并输出 thumb_media_id")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "用法: go run . cover [--title <标题>] [--subtitle <副标题>] [--tab <title|id>] [--out <dir>] [--font <file>] [--upload] [<documentId>]")
+		fs.PrintDefaults()
+	}
+	_ = fs.Parse(args)
+
+	cfg, err := loadConfig(*configPath)
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+
+	// 先检查字体，避免获取文档之后才发现无法生成封面
+	if *fontPath != "" {
+		cfg.Cover.Font = *fontPath
+	}
+	f, err := loadCoverFont(cfg.Cover.Font)
+	if err != nil {
+		return err
+	}
+
+	if *title == "" {
+		if fs.NArg() < 1 {
+			fs.Usage()
+			os.Exit(2)
+		}
+		srv, _, err := newDocsService(ctx, *proxyAddr)
+		if err != nil {
+			return err
+		}
+		fmt.Println("正在从 Google Docs 获取文档标题...")
+		articles, err := processDocument(srv, fs.Arg(0), cfg, *tab, false)
+		if err != nil {
+			return err
+		}
+		article := articles[0]
+		*title = article.Title
+		if *subtitle == "" {
+			*subtitle = article.Subtitle
+		}
+	}
+	if *subtitle == "" {
+		*subtitle = cfg.Cover.Subtitle
+	}
+
+	var background image.Image
+	if cfg.Cover.Background != "" {
+		file, err := os.Open(cfg.Cover.Background)
+		if err != nil {
+			return fmt.Errorf("无法读取封面背景: %v", err)
+		}
+		background, _, err = image.Decode(file)
+		file.Close()
+		if err != nil {
+			return fmt.Errorf("无法解码封面背景: %v", err)
+		}
+	}
+	if err := os.MkdirAll(*outDir, 0755); err != nil {
+		return fmt.Errorf("无法创建输出目录: %v", err)
+	}
+
+	var mainCover []byte
+	for i, size := range coverSizes {
+		img, err := renderCover(cfg.Cover, f, background, *title, *subtitle, size.Width, size.Height)
+		if err != nil {
+			return err
+		}
+		data, err := encodeJPEG(img, 90)
+		if err != nil {
+			return err
+		}
+		path := filepath.Join(*outDir, size.Name)
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			return fmt.Errorf("无法保存封面: %v", err)
+		}
+		fmt.Printf("已生成封面 %s (%dx%d, %s)\n", path, size.Width, size.Height, formatBytes(len(data)))
+		if i == 0 {
+			mainCover = data
+		}
+	}
+
+	if *upload {
+		client, err := newWeChatClient(cfg.WeChat)
+		if err != nil {
+			return err
+		}
+		mediaID, url, err := client.addMaterial(ctx, "image", coverSizes[0].Name, mainCover)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("已上传封面为永久素材\nthumb_media_id: %s\nurl: %s\n", mediaID, url)
+	}
+	return nil
+}
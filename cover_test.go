@@ -0,0 +1,33 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/image/font/gofont/goregular"
+)
+
+// 不包含中文字形的字体会把标题画成方框，加载时就要报错
+func TestLoadCoverFontRequiresCJK(t *testing.T) {
+	dir := t.TempDir()
+	latin := filepath.Join(dir, "Go-Regular.ttf")
+	if err := os.WriteFile(latin, goregular.TTF, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loadCoverFont(latin); err == nil || !strings.Contains(err.Error(), "中文字形") {
+		t.Errorf("西文字体的错误 = %v, 期望提示缺少中文字形", err)
+	}
+
+	broken := filepath.Join(dir, "broken.ttf")
+	if err := os.WriteFile(broken, []byte("not a font"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loadCoverFont(broken); err == nil {
+		t.Error("无法解析的字体文件应该报错")
+	}
+	if _, err := loadCoverFont(filepath.Join(dir, "missing.ttf")); err == nil {
+		t.Error("不存在的字体文件应该报错")
+	}
+}
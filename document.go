@@ -0,0 +1,701 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"google.golang.org/api/docs/v1"
+)
+
+// 有序列表使用的列表符号类型
+var orderedGlyphTypes = map[string]bool{
+	"DECIMAL":      true,
+	"ZERO_DECIMAL": true,
+	"UPPER_ALPHA":  true,
+	"ALPHA":        true,
+	"UPPER_ROMAN":  true,
+	"ROMAN":        true,
+}
+
+// Google Docs 段落对齐方式到 CSS text-align 的映射，START 为默认值
+var paragraphAlignments = map[string]string{
+	"CENTER":    "center",
+	"END":       "right",
+	"JUSTIFIED": "justify",
+}
+
+// docBuilder 把 docs.Document 转换为中间文档模型
+type docBuilder struct {
+	cfg       *Config
+	doc       *docs.Document
+	out       *Document
+	footnotes map[string]*Footnote
+	// 当前正在构建的列表栈，下标为嵌套层级
+	lists  []*List
+	listId string
+	// ranges 是命名范围标记的隐藏内容与分割点，segment 是正在构建的分段 (正文为空，脚注为脚注 ID)
+	ranges  *publishRanges
+	segment string
+	// parts 是在分割点之前已经完成的文章
+	parts []*Document
+	// warnedEquation 为 true 时已经提示过无法读取的公式
+	warnedEquation bool
+	// callout 不为 nil 时内容加入提示框，outer 是提示框之前已经生成的内容
+	callout       *Callout
+	calloutSyntax calloutSyntax
+	outer         []Block
+}
+
+// buildDocument 遍历 Google Docs 的内容元素，按过滤规则与命名范围决定发布的内容。
+// 文档中有分割点时返回多篇文章，Part 从 1 开始编号
+func buildDocument(doc *docs.Document, cfg *Config) []*Document {
+	b := &docBuilder{
+		cfg:       cfg,
+		doc:       doc,
+		out:       &Document{Title: doc.Title},
+		footnotes: map[string]*Footnote{},
+		ranges:    newPublishRanges(doc, cfg.Target),
+	}
+
+	filter := newSectionFilter(cfg.Filters)
+content:
+	for _, content := range doc.Body.Content {
+		if b.ranges.cutBefore(content.EndIndex) {
+			b.cut()
+		}
+		if b.ranges.isHidden("", content.StartIndex, content.EndIndex) {
+			continue
+		}
+		// --- 1. 处理段落 (Paragraph) ---
+		if content.Paragraph != nil {
+			para := content.Paragraph
+			style := para.ParagraphStyle.NamedStyleType
+			paraText := strings.TrimSpace(paragraphText(para))
+
+			// 标题与副标题总是记录下来 (用于封面)，是否输出由过滤规则决定
+			if style == "TITLE" {
+				b.out.Title = paraText
+			}
+			if style == "SUBTITLE" && b.out.Subtitle == "" {
+				b.out.Subtitle = paraText
+			}
+
+			switch filter.paragraph(style, paraText) {
+			case filterStop:
+				fmt.Printf("\n检测到 “%s”，已停止后续内容转换。\n", paraText)
+				break content
+			case filterDrop:
+				if style == "TITLE" {
+					fmt.Println("已忽略文档标题。")
+				}
+				continue
+			}
+
+			if para = b.calloutParagraph(para, paraText); para == nil {
+				continue
+			}
+			b.addParagraph(para)
+			b.addPositionedImages(para)
+		} else if content.SectionBreak != nil { // --- 2. 分节符 ---
+			// 文档开头总有一个分节符，addDivider 会忽略它
+			page := false
+			if style := content.SectionBreak.SectionStyle; style != nil {
+				page = style.SectionType == "NEXT_PAGE"
+			}
+			b.interruptCallout()
+			b.addBreak(page)
+		} else if content.Table != nil { // --- 3. 处理表格 (Table) ---
+			if filter.table() == filterDrop {
+				continue
+			}
+			b.interruptCallout()
+			if b.calloutTableContent(content.Table) {
+				continue
+			}
+			b.closeLists()
+			if table := b.buildTable(content.Table); table != nil {
+				b.out.Blocks = append(b.out.Blocks, table)
+			}
+		}
+	}
+	filter.finish()
+	b.finishPart()
+	if len(b.parts) == 0 {
+		return []*Document{b.out}
+	}
+	if len(b.parts) > 1 {
+		for i, part := range b.parts {
+			part.Part = i + 1
+		}
+	}
+	return b.parts
+}
+
+// addBreak 处理水平线、分节符与分页符：page 为 true (分页符或下一页分节符) 且配置了 split_at_page_breaks 时分割文章，否则插入分隔线
+func (b *docBuilder) addBreak(page bool) {
+	if page && b.cfg.SplitAtPageBreaks {
+		if len(b.out.Blocks) > 0 {
+			b.cut()
+		}
+		return
+	}
+	b.closeLists()
+	// 文章开头与连续的分隔线没有意义
+	if n := len(b.out.Blocks); n == 0 {
+		return
+	} else if _, ok := b.out.Blocks[n-1].(*Divider); ok {
+		return
+	}
+	b.out.Blocks = append(b.out.Blocks, &Divider{})
+}
+
+// splitBreaks 在段落中的水平线与分页符处拆分段落，前后的内容各自作为段落添加。
+// 段落中没有水平线与分页符时返回 false
+func (b *docBuilder) splitBreaks(para *docs.Paragraph) bool {
+	found := false
+	var elements []*docs.ParagraphElement
+	flush := func() {
+		if len(elements) > 0 {
+			b.addParagraph(&docs.Paragraph{Elements: elements, ParagraphStyle: para.ParagraphStyle, Bullet: para.Bullet})
+		}
+		elements = nil
+	}
+	for _, elem := range para.Elements {
+		if elem.HorizontalRule == nil && elem.PageBreak == nil {
+			elements = append(elements, elem)
+			continue
+		}
+		found = true
+		flush()
+		b.addBreak(elem.PageBreak != nil)
+	}
+	if found {
+		flush()
+	}
+	return found
+}
+
+// cut 在分割点结束当前文章，之后的内容属于新的一篇，脚注重新编号
+func (b *docBuilder) cut() {
+	b.finishPart()
+	b.out = &Document{Title: b.out.Title, Subtitle: b.out.Subtitle}
+	b.footnotes = map[string]*Footnote{}
+	fmt.Printf("\n--- 分割点: 第 %d 篇 ---\n", len(b.parts)+1)
+}
+
+// finishPart 完成当前文章，去掉末尾的分隔线。没有内容的文章 (如位于文档开头的分割点之前) 被丢弃
+func (b *docBuilder) finishPart() {
+	if b.callout != nil && b.calloutSyntax == calloutFence {
+		fmt.Printf("警告: 提示框 “%s” 缺少结束的 :::，已在文章末尾结束。\n", b.callout.Title)
+	}
+	b.closeCallout()
+	b.closeLists()
+	for n := len(b.out.Blocks); n > 0; n-- {
+		if _, ok := b.out.Blocks[n-1].(*Divider); !ok {
+			break
+		}
+		b.out.Blocks = b.out.Blocks[:n-1]
+	}
+	if len(b.out.Blocks) == 0 {
+		return
+	}
+	b.out.Blocks = attachCaptions(b.out.Blocks, b.cfg.Figures)
+	b.parts = append(b.parts, b.out)
+}
+
+// paragraphText 拼接段落中所有文本
+func paragraphText(para *docs.Paragraph) string {
+	var sb strings.Builder
+	for _, elem := range para.Elements {
+		if elem.TextRun != nil {
+			sb.WriteString(elem.TextRun.Content)
+		}
+	}
+	return sb.String()
+}
+
+func (b *docBuilder) addParagraph(para *docs.Paragraph) {
+	if b.splitBreaks(para) {
+		return
+	}
+	level := 0
+	switch para.ParagraphStyle.NamedStyleType {
+	case "HEADING_1":
+		level = 1
+	case "HEADING_2":
+		level = 2
+	case "HEADING_3":
+		level = 3
+	}
+
+	if level > 0 {
+		b.closeLists()
+		inlines := b.buildInlines(para.Elements)
+		// 标题使用主题字号
+		for i, in := range inlines {
+			if t, ok := in.(*Text); ok && t.Style.FontSize != 0 {
+				style := t.Style
+				style.FontSize = 0
+				inlines[i] = &Text{Text: t.Text, Style: style}
+			}
+		}
+		if !isBlankInlines(inlines) {
+			b.out.Blocks = append(b.out.Blocks, &Heading{Level: level, Inlines: mergeTexts(inlines)})
+		}
+		return
+	}
+
+	if para.Bullet != nil {
+		b.addListItem(para)
+		return
+	}
+
+	inlines := b.buildInlines(para.Elements)
+	if isBlankInlines(inlines) {
+		// 空段落不打断列表
+		return
+	}
+	b.closeLists()
+
+	if img := displayFormula(inlines); img != nil {
+		b.out.Blocks = append(b.out.Blocks, &Figure{Image: img})
+		return
+	}
+
+	if url, caption, ok := parseQRShortcode(plainText(inlines)); ok {
+		fig, err := qrCodeFigure(url, caption)
+		if err != nil {
+			fmt.Printf("忽略二维码短代码: %v\n", err)
+			return
+		}
+		b.out.Blocks = append(b.out.Blocks, fig)
+		return
+	}
+
+	template := &Paragraph{Align: paragraphAlignments[para.ParagraphStyle.Alignment]}
+	if dim := para.ParagraphStyle.IndentStart; dim != nil && dim.Unit == "PT" {
+		template.Indent = dim.Magnitude
+	}
+	if dim := para.ParagraphStyle.IndentFirstLine; dim != nil && dim.Unit == "PT" {
+		// 首行缩进在 API 中是相对于页面的位置，需要减去左缩进
+		template.FirstLineIndent = dim.Magnitude - template.Indent
+	}
+	blocks := b.splitImages(inlines, template)
+
+	if template.Indent > 0 && b.cfg.IndentAsBlockquote {
+		for _, block := range blocks {
+			if p, ok := block.(*Paragraph); ok {
+				p.Indent, p.FirstLineIndent = 0, 0
+			}
+		}
+		// 连续的缩进段落合并为同一个引用块
+		if n := len(b.out.Blocks); n > 0 {
+			if quote, ok := b.out.Blocks[n-1].(*Blockquote); ok {
+				quote.Blocks = append(quote.Blocks, blocks...)
+				return
+			}
+		}
+		b.out.Blocks = append(b.out.Blocks, &Blockquote{Blocks: blocks})
+		return
+	}
+	b.out.Blocks = append(b.out.Blocks, blocks...)
+}
+
+// splitImages 把段落中的图片 (行内公式除外) 拆成单独的段落，图片前后的文本各自成段，
+// 新段落沿用 template 的对齐与缩进。居中的单独图片转换为 Figure
+func (b *docBuilder) splitImages(inlines []Inline, template *Paragraph) []Block {
+	var blocks []Block
+	var current []Inline
+	newParagraph := func(inlines []Inline) *Paragraph {
+		p := *template
+		p.Inlines = inlines
+		return &p
+	}
+	flush := func() {
+		if !isBlankInlines(current) {
+			blocks = append(blocks, newParagraph(current))
+		}
+		current = nil
+	}
+	for _, in := range inlines {
+		// 行内公式与文字排在一起
+		if img, ok := in.(*Image); ok && img.Formula == nil {
+			flush()
+			if template.Align == "center" {
+				blocks = append(blocks, &Figure{Image: img})
+			} else {
+				blocks = append(blocks, newParagraph([]Inline{img}))
+			}
+			continue
+		}
+		current = append(current, in)
+	}
+	flush()
+	return blocks
+}
+
+// addListItem 把带项目符号的段落加入当前列表，根据嵌套层级创建或关闭子列表
+func (b *docBuilder) addListItem(para *docs.Paragraph) {
+	// 换了另一个列表，重新开始
+	if para.Bullet.ListId != b.listId {
+		b.closeLists()
+		b.listId = para.Bullet.ListId
+	}
+	depth := int(para.Bullet.NestingLevel)
+	// 层级不能跳跃，最多比当前列表深一层
+	if depth > len(b.lists) {
+		depth = len(b.lists)
+	}
+	if depth < len(b.lists)-1 {
+		b.lists = b.lists[:depth+1]
+	}
+	ordered, checklist := b.isOrdered(para.Bullet), b.isChecklist(para.Bullet)
+	// 同一层级的列表类型发生变化时，另起一个列表
+	if depth < len(b.lists) && (b.lists[depth].Ordered != ordered || b.lists[depth].Checklist != checklist) {
+		b.lists = b.lists[:depth]
+	}
+
+	if depth == len(b.lists) {
+		list := &List{Ordered: ordered, Checklist: checklist}
+		if depth == 0 {
+			b.out.Blocks = append(b.out.Blocks, list)
+		} else {
+			parent := b.lists[depth-1]
+			if len(parent.Items) == 0 {
+				parent.Items = append(parent.Items, &ListItem{})
+			}
+			last := parent.Items[len(parent.Items)-1]
+			last.Blocks = append(last.Blocks, list)
+		}
+		b.lists = append(b.lists, list)
+	}
+
+	list := b.lists[depth]
+	inlines := b.buildInlines(para.Elements)
+	list.Items = append(list.Items, &ListItem{
+		Blocks:  []Block{&Paragraph{Inlines: inlines}},
+		Checked: checklist && isChecked(inlines),
+	})
+}
+
+func (b *docBuilder) closeLists() {
+	b.lists = nil
+	b.listId = ""
+}
+
+// nestingLevel 返回列表定义中项目符号所在层级的设置，找不到时返回 nil
+func (b *docBuilder) nestingLevel(bullet *docs.Bullet) *docs.NestingLevel {
+	list, ok := b.doc.Lists[bullet.ListId]
+	if !ok || list.ListProperties == nil {
+		return nil
+	}
+	levels := list.ListProperties.NestingLevels
+	if int(bullet.NestingLevel) >= len(levels) {
+		return nil
+	}
+	return levels[bullet.NestingLevel]
+}
+
+// isOrdered 根据列表定义中对应层级的符号类型判断是否为有序列表
+func (b *docBuilder) isOrdered(bullet *docs.Bullet) bool {
+	level := b.nestingLevel(bullet)
+	return level != nil && orderedGlyphTypes[level.GlyphType]
+}
+
+// isChecklist 判断是否为核对清单：API 中核对清单的层级既没有编号类型也没有项目符号
+func (b *docBuilder) isChecklist(bullet *docs.Bullet) bool {
+	level := b.nestingLevel(bullet)
+	return level != nil && level.GlyphSymbol == "" &&
+		(level.GlyphType == "" || level.GlyphType == "GLYPH_TYPE_UNSPECIFIED")
+}
+
+// isChecked 判断核对清单项是否已勾选：API 不返回勾选状态，勾选后 Google Docs 会给整项文字加删除线
+func isChecked(inlines []Inline) bool {
+	checked := false
+	for _, in := range inlines {
+		t, ok := in.(*Text)
+		if !ok || strings.TrimSpace(t.Text) == "" {
+			continue
+		}
+		if !t.Style.Strikethrough {
+			return false
+		}
+		checked = true
+	}
+	return checked
+}
+
+// buildInlines 把段落元素转换为行内元素，并合并样式相同的相邻文本
+func (b *docBuilder) buildInlines(elements []*docs.ParagraphElement) []Inline {
+	var inlines []Inline
+	for _, elem := range elements {
+		if b.ranges.isHidden(b.segment, elem.StartIndex, elem.EndIndex) {
+			continue
+		}
+		switch {
+		case elem.TextRun != nil:
+			style := newTextStyle(elem.TextRun.TextStyle)
+			// 段落末尾的换行符不属于正文，段内的 \v 是手动换行
+			content := b.ranges.visibleText(b.segment, elem.StartIndex, elem.TextRun.Content)
+			content = strings.ReplaceAll(content, "\n", "")
+			for i, part := range strings.Split(content, "\v") {
+				if i > 0 {
+					inlines = append(inlines, &LineBreak{})
+				}
+				if part != "" {
+					inlines = append(inlines, &Text{Text: part, Style: style})
+				}
+			}
+		case elem.InlineObjectElement != nil:
+			if img := b.buildImage(elem.InlineObjectElement.InlineObjectId); img != nil {
+				inlines = append(inlines, img)
+			}
+		case elem.FootnoteReference != nil:
+			inlines = append(inlines, b.footnoteRef(elem.FootnoteReference.FootnoteId))
+		case elem.RichLink != nil:
+			if t := richLinkText(elem.RichLink); t != nil {
+				inlines = append(inlines, t)
+			}
+		case elem.Person != nil:
+			if t := personText(elem.Person, b.cfg.Chips.ShowEmail); t != nil {
+				inlines = append(inlines, t)
+			}
+		case elem.DateElement != nil:
+			if t := dateText(elem.DateElement, b.cfg.Chips.Locale); t != nil {
+				inlines = append(inlines, t)
+			}
+		case elem.Equation != nil:
+			// Docs API 只返回公式的位置，不返回公式内容
+			if !b.warnedEquation {
+				fmt.Println("警告: 无法读取 Google Docs 公式编辑器插入的公式，已忽略；请改用 $...$ 或 $$...$$ 书写 LaTeX 公式，并在配置文件中开启 math.enabled。")
+				b.warnedEquation = true
+			}
+		}
+	}
+	return b.expandFormulas(mergeTexts(inlines))
+}
+
+func newTextStyle(ts *docs.TextStyle) TextStyle {
+	var style TextStyle
+	if ts == nil {
+		return style
+	}
+	style.Bold = ts.Bold
+	style.Italic = ts.Italic
+	style.Strikethrough = ts.Strikethrough
+	if ts.Link != nil {
+		style.Link = ts.Link.Url
+	}
+	// 链接在 Google Docs 中默认带下划线，不需要额外输出
+	style.Underline = ts.Underline && style.Link == ""
+	style.SmallCaps = ts.SmallCaps
+	if ts.BaselineOffset == "SUPERSCRIPT" || ts.BaselineOffset == "SUBSCRIPT" {
+		style.Baseline = ts.BaselineOffset
+	}
+	style.Color = docsColorHex(ts.ForegroundColor)
+	style.Background = docsColorHex(ts.BackgroundColor)
+	if ts.FontSize != nil && ts.FontSize.Unit == "PT" {
+		style.FontSize = ts.FontSize.Magnitude
+	}
+	return style
+}
+
+func (b *docBuilder) buildImage(objId string) *Image {
+	inlineObj, ok := b.doc.InlineObjects[objId]
+	if !ok || inlineObj.InlineObjectProperties == nil || inlineObj.InlineObjectProperties.EmbeddedObject == nil {
+		return nil
+	}
+	return b.embeddedImage(objId, inlineObj.InlineObjectProperties.EmbeddedObject)
+}
+
+// addPositionedImages 把锚定在段落上的浮动图片放在段落之后，作为单独的图片输出
+func (b *docBuilder) addPositionedImages(para *docs.Paragraph) {
+	for _, img := range b.positionedImages(para) {
+		b.closeLists()
+		b.out.Blocks = append(b.out.Blocks, &Figure{Image: img})
+	}
+}
+
+// positionedImages 返回锚定在段落上的浮动图片
+func (b *docBuilder) positionedImages(para *docs.Paragraph) []*Image {
+	var images []*Image
+	for _, id := range para.PositionedObjectIds {
+		obj, ok := b.doc.PositionedObjects[id]
+		if !ok || obj.PositionedObjectProperties == nil || obj.PositionedObjectProperties.EmbeddedObject == nil {
+			continue
+		}
+		if img := b.embeddedImage(id, obj.PositionedObjectProperties.EmbeddedObject); img != nil {
+			images = append(images, img)
+		}
+	}
+	return images
+}
+
+// embeddedImage 把嵌入对象 (图片或绘图) 转换为图片，无法获取内容的绘图返回 nil
+func (b *docBuilder) embeddedImage(objId string, obj *docs.EmbeddedObject) *Image {
+	title, description := obj.Title, obj.Description
+	var drawingID string
+	if obj.EmbeddedDrawingProperties != nil {
+		drawingID, title = drawingFileID(title)
+		if drawingID == "" {
+			drawingID, description = drawingFileID(description)
+		} else {
+			_, description = drawingFileID(description)
+		}
+		if drawingID == "" {
+			fmt.Printf("忽略绘图 %s: Docs API 无法读取绘图内容，请在绘图的替代文字中粘贴绘图在 Drive 中的链接\n", objId)
+			return nil
+		}
+	}
+	img := &Image{
+		ObjectID: objId,
+		URL:      placeholderImageURL(objId, ".png"),
+	}
+	var skipTitle, skipDesc bool
+	img.Title, skipTitle = stripNoWatermark(strings.TrimSpace(title))
+	img.Description, skipDesc = stripNoWatermark(strings.TrimSpace(description))
+	img.NoWatermark = skipTitle || skipDesc
+	if drawingID != "" {
+		img.SourceURL = drawingExportURL(drawingID)
+	}
+	if props := obj.ImageProperties; props != nil {
+		img.SourceURL = props.ContentUri
+		img.Rotation = props.Angle
+		if crop := props.CropProperties; crop != nil {
+			img.Crop = ImageCrop{
+				Left:   crop.OffsetLeft,
+				Right:  crop.OffsetRight,
+				Top:    crop.OffsetTop,
+				Bottom: crop.OffsetBottom,
+				Angle:  crop.Angle,
+			}
+		}
+	}
+	if obj.Size != nil && obj.Size.Width != nil && obj.Size.Width.Unit == "PT" {
+		if pageWidth := b.contentWidth(); pageWidth > 0 {
+			img.WidthPercent = math.Min(100, obj.Size.Width.Magnitude/pageWidth*100)
+		}
+	}
+	// 替代文字优先使用 "说明"，其次是 "标题"
+	switch {
+	case img.Description != "":
+		img.Alt = img.Description
+	case img.Title != "":
+		img.Alt = img.Title
+	default:
+		img.Alt = "Image from Google Docs"
+	}
+	return img
+}
+
+// contentWidth 返回页面正文区域的宽度 (pt)，即纸张宽度减去左右页边距
+func (b *docBuilder) contentWidth() float64 {
+	style := b.doc.DocumentStyle
+	if style == nil || style.PageSize == nil || style.PageSize.Width == nil {
+		return 0
+	}
+	width := style.PageSize.Width.Magnitude
+	if style.MarginLeft != nil {
+		width -= style.MarginLeft.Magnitude
+	}
+	if style.MarginRight != nil {
+		width -= style.MarginRight.Magnitude
+	}
+	return width
+}
+
+// placeholderImageURL 返回图片的占位符地址，文件名与下载到本地的图片文件名一致
+func placeholderImageURL(objId, ext string) string {
+	return fmt.Sprintf("https://your-cdn.com/path/to/image-for-%s%s", objId, ext)
+}
+
+// footnoteRef 创建脚注引用，脚注内容在第一次被引用时构建，编号按引用顺序分配
+func (b *docBuilder) footnoteRef(id string) *FootnoteRef {
+	if fn, ok := b.footnotes[id]; ok {
+		return &FootnoteRef{ID: id, Number: fn.Number}
+	}
+	fn := &Footnote{ID: id, Number: len(b.out.Footnotes) + 1}
+	b.footnotes[id] = fn
+	b.out.Footnotes = append(b.out.Footnotes, fn)
+	if content, ok := b.doc.Footnotes[id]; ok {
+		segment := b.segment
+		b.segment = id
+		defer func() { b.segment = segment }()
+		for _, elem := range content.Content {
+			if elem.Paragraph == nil {
+				continue
+			}
+			inlines := b.buildInlines(elem.Paragraph.Elements)
+			if !isBlankInlines(inlines) {
+				fn.Blocks = append(fn.Blocks, &Paragraph{Inlines: trimInlines(inlines)})
+			}
+		}
+	}
+	return &FootnoteRef{ID: id, Number: fn.Number}
+}
+
+// buildTable 把表格转换为模型，单元格中的多个段落以空格连接，
+// 单元格只能包含行内元素，锚定在单元格段落上的浮动图片作为行内图片放在段落之后
+func (b *docBuilder) buildTable(table *docs.Table) *Table {
+	if len(table.TableRows) == 0 {
+		return nil
+	}
+	t := &Table{}
+	// 遍历行
+	for _, row := range table.TableRows {
+		r := &TableRow{}
+		// 遍历单元格
+		for _, cell := range row.TableCells {
+			var inlines []Inline
+			for _, cellContent := range cell.Content {
+				if cellContent.Paragraph == nil {
+					continue
+				}
+				paraInlines := b.buildInlines(cellContent.Paragraph.Elements)
+				for _, img := range b.positionedImages(cellContent.Paragraph) {
+					paraInlines = append(paraInlines, img)
+				}
+				if isBlankInlines(paraInlines) {
+					continue
+				}
+				if len(inlines) > 0 {
+					inlines = append(inlines, &Text{Text: " "})
+				}
+				inlines = append(inlines, paraInlines...)
+			}
+			r.Cells = append(r.Cells, &TableCell{Inlines: mergeTexts(inlines)})
+		}
+		t.Rows = append(t.Rows, r)
+	}
+	return t
+}
+
+// isBlankInlines 判断行内元素是否只包含空白文本
+func isBlankInlines(inlines []Inline) bool {
+	for _, in := range inlines {
+		switch v := in.(type) {
+		case *Text:
+			if strings.TrimSpace(v.Text) != "" {
+				return false
+			}
+		case *LineBreak:
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// trimInlines 去掉首尾文本的空白
+func trimInlines(inlines []Inline) []Inline {
+	if n := len(inlines); n > 0 {
+		if t, ok := inlines[n-1].(*Text); ok {
+			inlines[n-1] = &Text{Text: strings.TrimRight(t.Text, " \t"), Style: t.Style}
+		}
+		if t, ok := inlines[0].(*Text); ok {
+			inlines[0] = &Text{Text: strings.TrimLeft(t.Text, " \t"), Style: t.Style}
+		}
+	}
+	return inlines
+}
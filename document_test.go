@@ -0,0 +1,131 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+
+	"google.golang.org/api/docs/v1"
+)
+
+func positionedTestParagraph(text string, objectIDs ...string) *docs.StructuralElement {
+	return &docs.StructuralElement{Paragraph: &docs.Paragraph{
+		ParagraphStyle:      &docs.ParagraphStyle{NamedStyleType: "NORMAL_TEXT"},
+		Elements:            []*docs.ParagraphElement{{TextRun: &docs.TextRun{Content: text}}},
+		PositionedObjectIds: objectIDs,
+	}}
+}
+
+func positionedTestObject(title string) docs.PositionedObject {
+	return docs.PositionedObject{PositionedObjectProperties: &docs.PositionedObjectProperties{
+		EmbeddedObject: &docs.EmbeddedObject{Title: title, ImageProperties: &docs.ImageProperties{ContentUri: "https://example.com/" + title}},
+	}}
+}
+
+func singleCellTable(content ...*docs.StructuralElement) *docs.StructuralElement {
+	return &docs.StructuralElement{Table: &docs.Table{TableRows: []*docs.TableRow{
+		{TableCells: []*docs.TableCell{{Content: content}}},
+	}}}
+}
+
+func TestPositionedImagesInTables(t *testing.T) {
+	doc := &docs.Document{
+		Body: &docs.Body{Content: []*docs.StructuralElement{
+			{Table: &docs.Table{TableRows: []*docs.TableRow{
+				{TableCells: []*docs.TableCell{
+					{Content: []*docs.StructuralElement{positionedTestParagraph("名称\n")}},
+					{Content: []*docs.StructuralElement{positionedTestParagraph("截图\n")}},
+				}},
+				{TableCells: []*docs.TableCell{
+					{Content: []*docs.StructuralElement{positionedTestParagraph("首页\n")}},
+					{Content: []*docs.StructuralElement{positionedTestParagraph("\n", "kix.cell")}},
+				}},
+			}}},
+			singleCellTable(positionedTestParagraph("提示\n"), positionedTestParagraph("见下图\n", "kix.callout")),
+		}},
+		PositionedObjects: map[string]docs.PositionedObject{
+			"kix.cell":    positionedTestObject("cell"),
+			"kix.callout": positionedTestObject("callout"),
+		},
+	}
+	articles := buildDocument(doc, defaultConfig())
+	if len(articles) != 1 || len(articles[0].Blocks) != 2 {
+		t.Fatalf("期望一个表格和一个提示框, 得到 %#v", articles)
+	}
+
+	table, ok := articles[0].Blocks[0].(*Table)
+	if !ok {
+		t.Fatalf("第一个块 = %T, 期望 *Table", articles[0].Blocks[0])
+	}
+	cell := table.Rows[1].Cells[1]
+	if len(cell.Inlines) != 1 {
+		t.Fatalf("单元格内容 = %#v, 期望一张图片", cell.Inlines)
+	}
+	if img, ok := cell.Inlines[0].(*Image); !ok || img.ObjectID != "kix.cell" {
+		t.Errorf("单元格内容 = %#v, 期望浮动图片 kix.cell", cell.Inlines[0])
+	}
+
+	callout, ok := articles[0].Blocks[1].(*Callout)
+	if !ok {
+		t.Fatalf("第二个块 = %T, 期望 *Callout", articles[0].Blocks[1])
+	}
+	var found bool
+	for _, block := range callout.Blocks {
+		if fig, ok := block.(*Figure); ok && fig.Image.ObjectID == "kix.callout" {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("提示框中缺少浮动图片 kix.callout: %#v", callout.Blocks)
+	}
+}
+
+// 表格单元格中的图片、强调与脚注引用要按正常的行内元素渲染到数据卡片中，而不是被压平为文字
+func TestRenderTableCellInlines(t *testing.T) {
+	doc := &Document{
+		Blocks: []Block{&Table{Rows: []*TableRow{
+			{Cells: []*TableCell{
+				{Inlines: []Inline{&Text{Text: "名称"}}},
+				{Inlines: []Inline{&Text{Text: "截图"}}},
+			}},
+			{Cells: []*TableCell{
+				{Inlines: []Inline{&Text{Text: "首页", Style: TextStyle{Bold: true}}, &FootnoteRef{ID: "fn", Number: 1}}},
+				{Inlines: []Inline{&Image{URL: "https://example.com/cell.png", Alt: "cell"}}},
+			}},
+		}}},
+		Footnotes: []*Footnote{{ID: "fn", Number: 1, Blocks: []Block{&Paragraph{Inlines: []Inline{&Text{Text: "说明"}}}}}},
+	}
+	cfg := defaultConfig()
+	cfg.Typography = false
+	out, err := renderArticle(doc, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	html := string(out)
+
+	cells := regexp.MustCompile(`<p style="[^"]*"><strong style="[^"]*">([^<]*): </strong>(.*?)</p>`).FindAllStringSubmatch(html, -1)
+	if len(cells) != 2 {
+		t.Fatalf("期望两个数据单元格, 得到 %q", html)
+	}
+	if cells[0][1] != "名称" || !strings.Contains(cells[0][2], "<strong") || !strings.Contains(cells[0][2], "首页") || !strings.Contains(cells[0][2], "<sup") {
+		t.Errorf("第一个单元格 = %q, 期望粗体文字与脚注上标", cells[0][0])
+	}
+	if cells[1][1] != "截图" || !strings.Contains(cells[1][2], `<img src="https://example.com/cell.png"`) {
+		t.Errorf("第二个单元格 = %q, 期望图片", cells[1][0])
+	}
+	if strings.Contains(html, "截图: </strong>cell") {
+		t.Errorf("图片被压平为替代文字: %q", html)
+	}
+}
+
+// 只有文章中有需要导出的绘图时才申请 Drive 权限
+func TestHasDrawings(t *testing.T) {
+	photo := &Document{Blocks: []Block{&Figure{Image: &Image{SourceURL: "https://lh7-rt.googleusercontent.com/docsz/photo"}}}}
+	drawing := &Document{Footnotes: []*Footnote{{Blocks: []Block{&Paragraph{Inlines: []Inline{&Image{SourceURL: drawingExportURL("abc")}}}}}}}
+	if hasDrawings([]*Document{photo}) {
+		t.Error("普通图片不应该需要 Drive 权限")
+	}
+	if !hasDrawings([]*Document{photo, drawing}) {
+		t.Error("脚注中的绘图需要 Drive 权限")
+	}
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Docs API 不返回绘图的内容，也不返回从 Drive 插入的绘图对应的文件。
+// 作者在绘图的替代文字 (标题或说明) 中粘贴绘图的链接后，通过 Drive 导出为 PNG
+var reDrawingLink = regexp.MustCompile(`\s*https://docs\.google\.com/drawings/d/([-\w]+)\S*\s*`)
+
+// driveExportURL 是 Drive 导出接口的地址，下载时使用带授权的 HTTP 客户端
+const driveExportURL = "https://www.googleapis.com/drive/v3/files/"
+
+// drawingFileID 从替代文字中找出绘图的 Drive 文件 ID，返回去掉链接后的替代文字
+func drawingFileID(s string) (id, rest string) {
+	m := reDrawingLink.FindStringSubmatch(s)
+	if m == nil {
+		return "", s
+	}
+	return m[1], strings.TrimSpace(reDrawingLink.ReplaceAllString(s, " "))
+}
+
+// drawingExportURL 返回把绘图导出为 PNG 的地址
+func drawingExportURL(id string) string {
+	return fmt.Sprintf("%s%s/export?mimeType=image/png", driveExportURL, id)
+}
+
+// hasDrawings 判断文章中是否有需要通过 Drive 导出的绘图
+func hasDrawings(articles []*Document) bool {
+	found := false
+	for _, d := range articles {
+		forEachImage(d, func(img *Image) {
+			if strings.HasPrefix(img.SourceURL, driveExportURL) {
+				found = true
+			}
+		})
+	}
+	return found
+}
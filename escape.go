@@ -0,0 +1,58 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+)
+
+// mdContext 表示文本在 Markdown 中所处的上下文，不同上下文需要转义的字符不同
+type mdContext int
+
+const (
+	mdText      mdContext = iota // 普通段落文本
+	mdTableCell                  // 表格单元格，额外转义 "|"
+	mdLinkText                   // 链接文字，"[" "]" 已在普通转义中处理
+)
+
+// 在任意位置都可能被 goldmark 解释为标记的字符
+const mdInlineSpecials = "\\`*_[]<&~#"
+
+// escapeMarkdown 对 Google Docs 中的纯文本进行转义，使 goldmark 按原样渲染作者输入的内容
+func escapeMarkdown(text string, ctx mdContext) string {
+	var b strings.Builder
+	for _, r := range text {
+		if r < 0x80 && strings.ContainsRune(mdInlineSpecials, r) {
+			b.WriteByte('\\')
+		} else if r == '|' && ctx == mdTableCell {
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// 行首可能被解释为块级结构的模式: 标题、引用、列表项、有序列表，
+// 以及整行由 "-" "*" "_" "=" 组成的分隔线或 Setext 标题下划线 (如 "---"、"* * *"、"===")
+var (
+	reLineStartBlock   = regexp.MustCompile(`^([#>]|[-+=](\s|$)|-[-\s]*$|=[=\s]*$|\*[*\s]*$|_[_\s]*$)`)
+	reLineStartOrdered = regexp.MustCompile(`^(\d{1,9})([.)])`)
+)
+
+// escapeLineStart 转义段落开头会被误认为块级语法的内容，
+// 行首缩进也会被去掉，避免段落被当作缩进代码块
+func escapeLineStart(line string) string {
+	line = strings.TrimLeft(line, " \t")
+	if reLineStartBlock.MatchString(line) {
+		return "\\" + line
+	}
+	if m := reLineStartOrdered.FindStringSubmatchIndex(line); m != nil {
+		return line[:m[3]] + "\\" + line[m[3]:]
+	}
+	return line
+}
+
+// escapeLinkDestination 转义链接地址中会提前结束 Markdown 链接的字符
+func escapeLinkDestination(url string) string {
+	r := strings.NewReplacer(" ", "%20", "(", "%28", ")", "%29", "<", "%3C", ">", "%3E")
+	return r.Replace(url)
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
+)
+
+// convertGFM 用与渲染文章相同的 GFM 语法把 Markdown 转换为 HTML
+func convertGFM(t *testing.T, src string) string {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := goldmark.New(goldmark.WithExtensions(extension.GFM)).Convert([]byte(src), &buf); err != nil {
+		t.Fatal(err)
+	}
+	return buf.String()
+}
+
+var htmlTextEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", `"`, "&quot;")
+
+func TestEscapeParagraphRoundTrip(t *testing.T) {
+	tests := []struct{ in, want string }{
+		// 行首的块级语法
+		{"# 不是标题", ""},
+		{"#不是标题", ""},
+		{"> 不是引用", ""},
+		{"- 不是列表", ""},
+		{"+ 不是列表", ""},
+		{"* 不是列表", ""},
+		{"1. 不是有序列表", ""},
+		{"2) 不是有序列表", ""},
+		{"---", ""},
+		{"- - -", ""},
+		{"***", ""},
+		{"* * *", ""},
+		{"___", ""},
+		{"===", ""},
+		{"    不是代码块", "不是代码块"},
+		// 行内语法
+		{"*不是强调*", ""},
+		{"_不是强调_", ""},
+		{"**不是粗体**", ""},
+		{"~~不是删除线~~", ""},
+		{"`不是代码`", ""},
+		{"<b>不是 HTML</b>", ""},
+		{"[不是链接](a.md)", ""},
+		{"![不是图片](a.png)", ""},
+		{"&amp; 不是实体", ""},
+		{`C:\path\to\file`, ""},
+		{"snake_case_name 与 2*3*4", ""},
+		// 不需要转义的文字保持原样
+		{"1,000 元，版本 1.0", ""},
+		{"a - b = c", ""},
+	}
+	for _, tt := range tests {
+		want := tt.want
+		if want == "" {
+			want = tt.in
+		}
+		src := escapeLines(escapeMarkdown(tt.in, mdText))
+		got := convertGFM(t, src)
+		if expected := "<p>" + htmlTextEscaper.Replace(want) + "</p>\n"; got != expected {
+			t.Errorf("%q 转义为 %q\n渲染为 %q\n期望   %q", tt.in, src, got, expected)
+		}
+	}
+}
+
+// 手动换行之后的一行也可能被解释为块级语法，如 Setext 标题的下划线
+func TestEscapeLinesAfterLineBreak(t *testing.T) {
+	for _, line := range []string{"===", "---", "- 列表", "# 标题", "> 引用"} {
+		src := escapeLines(escapeMarkdown("正文", mdText) + "\\\n" + escapeMarkdown(line, mdText))
+		got := convertGFM(t, src)
+		if expected := "<p>正文<br>\n" + htmlTextEscaper.Replace(line) + "</p>\n"; got != expected {
+			t.Errorf("%q 渲染为 %q, 期望 %q", src, got, expected)
+		}
+	}
+}
+
+func TestEscapeTableCell(t *testing.T) {
+	for _, cell := range []string{"a|b", "`x|y`", "*强调*", "<td>"} {
+		src := "| 表头 |\n| --- |\n| " + escapeMarkdown(cell, mdTableCell) + " |\n"
+		got := convertGFM(t, src)
+		if expected := "<td>" + htmlTextEscaper.Replace(cell) + "</td>"; !strings.Contains(got, expected) {
+			t.Errorf("单元格 %q 渲染为 %q, 期望包含 %q", cell, got, expected)
+		}
+	}
+}
+
+func TestEscapeLink(t *testing.T) {
+	tests := []struct{ text, url, want string }{
+		{"文字", "https://example.com/a b", `<a href="https://example.com/a%20b">文字</a>`},
+		{"文字", "https://zh.wikipedia.org/wiki/Go_(语言)", `<a href="https://zh.wikipedia.org/wiki/Go_%28%E8%AF%AD%E8%A8%80%29">文字</a>`},
+		{"文字", "https://example.com/<x>", `<a href="https://example.com/%3Cx%3E">文字</a>`},
+		{"[方括号]", "https://example.com", `<a href="https://example.com">[方括号]</a>`},
+		{"*星号*", "https://example.com", `<a href="https://example.com">*星号*</a>`},
+	}
+	for _, tt := range tests {
+		src := "[" + escapeMarkdown(tt.text, mdLinkText) + "](" + escapeLinkDestination(tt.url) + ")"
+		if got := convertGFM(t, src); got != "<p>"+tt.want+"</p>\n" {
+			t.Errorf("%q 渲染为 %q, 期望 %q", src, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// 以 "图 1：" "Figure 2." 这类编号开头的段落视为图片说明，编号会被重新生成
+var reCaptionPrefix = regexp.MustCompile(`^(?:图|Figure|Fig\.)\s*(?:\d+(?:[.\-]\d+)*\s*[.:：、]?|[.:：、])\s*`)
+
+// standaloneImage 返回单独成段的图片
+func standaloneImage(block Block) *Image {
+	switch v := block.(type) {
+	case *Figure:
+		return v.Image
+	case *Paragraph:
+		if len(v.Inlines) == 1 {
+			if img, ok := v.Inlines[0].(*Image); ok {
+				return img
+			}
+		}
+	}
+	return nil
+}
+
+// captionInlines 判断段落是否为图片说明：全部为斜体，或以 "图"/"Figure" 编号开头。
+// 是说明时返回去掉编号前缀与整体斜体后的行内元素
+func captionInlines(block Block) ([]Inline, bool) {
+	p, ok := block.(*Paragraph)
+	if !ok || len(p.Inlines) == 0 {
+		return nil, false
+	}
+
+	allItalic := true
+	for _, in := range p.Inlines {
+		t, ok := in.(*Text)
+		if !ok {
+			// 包含图片等非文本元素的段落不是说明
+			if _, isBreak := in.(*LineBreak); !isBreak {
+				return nil, false
+			}
+			continue
+		}
+		if !t.Style.Italic && strings.TrimSpace(t.Text) != "" {
+			allItalic = false
+		}
+	}
+
+	inlines := make([]Inline, len(p.Inlines))
+	copy(inlines, p.Inlines)
+	first, _ := inlines[0].(*Text)
+	hasPrefix := first != nil && reCaptionPrefix.MatchString(strings.TrimSpace(first.Text))
+	if !allItalic && !hasPrefix {
+		return nil, false
+	}
+
+	if hasPrefix {
+		rest := reCaptionPrefix.ReplaceAllString(strings.TrimSpace(first.Text), "")
+		inlines[0] = &Text{Text: rest, Style: first.Style}
+	}
+	if allItalic {
+		for i, in := range inlines {
+			if t, ok := in.(*Text); ok {
+				style := t.Style
+				style.Italic = false
+				inlines[i] = &Text{Text: t.Text, Style: style}
+			}
+		}
+	}
+	return mergeTexts(trimInlines(inlines)), true
+}
+
+// attachCaptions 把紧跟在单独图片之后的说明段落合并为带说明的 Figure，并为其编号
+func attachCaptions(blocks []Block, cfg FigureConfig) []Block {
+	var out []Block
+	number := 0
+	for i := 0; i < len(blocks); i++ {
+		img := standaloneImage(blocks[i])
+		if img == nil || img.Formula != nil {
+			// 公式不是插图，不合并说明也不编号
+			out = append(out, blocks[i])
+			continue
+		}
+		fig, isFigure := blocks[i].(*Figure)
+		if isFigure && fig.Caption != nil {
+			// 已有说明的图片 (如二维码) 不再合并后面的段落，也不参与编号
+			out = append(out, fig)
+			continue
+		}
+		if !isFigure {
+			fig = &Figure{Image: img}
+		}
+		captioned := false
+		if i+1 < len(blocks) {
+			// 只有编号的说明 (如 "图 1") 去掉编号后为空，图片只显示自动生成的编号；
+			// 不编号时这样的段落不作为说明，保持原样
+			if caption, ok := captionInlines(blocks[i+1]); ok && (!isBlankInlines(caption) || !cfg.NoNumbering) {
+				if isBlankInlines(caption) {
+					caption = nil
+				}
+				fig.Caption = caption
+				if !cfg.NoNumbering {
+					number++
+					fig.Number = number
+					fig.Label = figureLabel(cfg, number)
+				}
+				captioned = true
+				i++
+			}
+		}
+		if !captioned && !isFigure {
+			// 没有说明的非居中图片保持原样
+			out = append(out, blocks[i])
+			continue
+		}
+		out = append(out, fig)
+	}
+	return out
+}
+
+// figureLabel 返回图片编号，例如 "图 1"
+func figureLabel(cfg FigureConfig, number int) string {
+	label := cfg.Label
+	if label == "" {
+		label = "图"
+	}
+	return label + " " + strconv.Itoa(number)
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func textParagraph(texts ...*Text) *Paragraph {
+	p := &Paragraph{}
+	for _, t := range texts {
+		p.Inlines = append(p.Inlines, t)
+	}
+	return p
+}
+
+func TestCaptionInlines(t *testing.T) {
+	italic := TextStyle{Italic: true}
+	tests := []struct {
+		name  string
+		block Block
+		want  []Inline
+		ok    bool
+	}{
+		{"编号前缀", textParagraph(&Text{Text: "图 3：系统架构"}), []Inline{&Text{Text: "系统架构"}}, true},
+		{"英文前缀", textParagraph(&Text{Text: "Figure 2. Overview"}), []Inline{&Text{Text: "Overview"}}, true},
+		{"多级编号", textParagraph(&Text{Text: "图1-2 流程"}), []Inline{&Text{Text: "流程"}}, true},
+		{"全部斜体", textParagraph(&Text{Text: "示意图", Style: italic}), []Inline{&Text{Text: "示意图"}}, true},
+		{"斜体前缀与正文", textParagraph(&Text{Text: "图 1：", Style: italic}, &Text{Text: "说明", Style: italic}),
+			[]Inline{&Text{Text: "说明"}}, true},
+		{"只有编号", textParagraph(&Text{Text: "图 1"}), nil, true},
+		{"普通段落", textParagraph(&Text{Text: "图书馆开放时间"}), nil, false},
+		{"部分斜体", textParagraph(&Text{Text: "强调", Style: italic}, &Text{Text: "正文"}), nil, false},
+		{"包含图片", &Paragraph{Inlines: []Inline{&Text{Text: "图 1", Style: italic}, &Image{}}}, nil, false},
+		{"不是段落", &Figure{Image: &Image{}}, nil, false},
+		{"空段落", &Paragraph{}, nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := captionInlines(tt.block)
+			if ok != tt.ok {
+				t.Fatalf("captionInlines ok = %v, 期望 %v", ok, tt.ok)
+			}
+			if ok && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("captionInlines = %#v, 期望 %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAttachCaptions(t *testing.T) {
+	img := func(id string) *Paragraph { return &Paragraph{Inlines: []Inline{&Image{ObjectID: id}}} }
+	type figure struct {
+		id, label, caption string
+	}
+	// summary 把结果概括为图片 (带编号与说明) 或段落文字，便于比较
+	summary := func(blocks []Block) []any {
+		var out []any
+		for _, b := range blocks {
+			switch v := b.(type) {
+			case *Figure:
+				caption := ""
+				for _, in := range v.Caption {
+					caption += in.(*Text).Text
+				}
+				out = append(out, figure{v.Image.ObjectID, v.Label, caption})
+			case *Paragraph:
+				if img := standaloneImage(v); img != nil {
+					out = append(out, "img:"+img.ObjectID)
+				} else {
+					out = append(out, v.Inlines[0].(*Text).Text)
+				}
+			}
+		}
+		return out
+	}
+
+	tests := []struct {
+		name   string
+		blocks []Block
+		cfg    FigureConfig
+		want   []any
+	}{
+		{"说明与编号", []Block{img("a"), textParagraph(&Text{Text: "图 5：架构"}), img("b"), textParagraph(&Text{Text: "Figure: 流程"})}, FigureConfig{},
+			[]any{figure{"a", "图 1", "架构"}, figure{"b", "图 2", "流程"}}},
+		{"没有说明的图片保持原样", []Block{img("a"), textParagraph(&Text{Text: "正文"})}, FigureConfig{},
+			[]any{"img:a", "正文"}},
+		{"只有编号的说明保留图片", []Block{img("a"), textParagraph(&Text{Text: "图 1"}), img("b"), textParagraph(&Text{Text: "Figure 2:"})}, FigureConfig{Label: "Figure"},
+			[]any{figure{"a", "Figure 1", ""}, figure{"b", "Figure 2", ""}}},
+		{"不编号时只有编号的段落不是说明", []Block{img("a"), textParagraph(&Text{Text: "图 1"})}, FigureConfig{NoNumbering: true},
+			[]any{"img:a", "图 1"}},
+		{"不编号", []Block{img("a"), textParagraph(&Text{Text: "图 1：架构"})}, FigureConfig{NoNumbering: true},
+			[]any{figure{"a", "", "架构"}}},
+		{"居中图片没有说明", []Block{&Figure{Image: &Image{ObjectID: "a"}}, textParagraph(&Text{Text: "正文"})}, FigureConfig{},
+			[]any{figure{"a", "", ""}, "正文"}},
+		{"已有说明的图片不编号", []Block{&Figure{Image: &Image{ObjectID: "qr"}, Caption: []Inline{&Text{Text: "扫码"}}}, textParagraph(&Text{Text: "图 1：其他"}), img("a"), textParagraph(&Text{Text: "图 2：架构"})}, FigureConfig{},
+			[]any{figure{"qr", "", "扫码"}, "图 1：其他", figure{"a", "图 1", "架构"}}},
+		{"公式不是插图", []Block{&Paragraph{Inlines: []Inline{&Image{ObjectID: "f", Formula: &Formula{Display: true}}}}, textParagraph(&Text{Text: "图 1：说明"})}, FigureConfig{},
+			[]any{"img:f", "图 1：说明"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := summary(attachCaptions(tt.blocks, tt.cfg)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("attachCaptions = %v, 期望 %v", got, tt.want)
+			}
+		})
+	}
+}
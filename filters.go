@@ -0,0 +1,147 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// 文档中的过滤标记，单独成段时生效。
+// wechat:skip 与 wechat:end 之间的内容不发布；以 wechat:skip 开头的段落 (后面还有文字) 只跳过这一段；
+// wechat:stop 停止转换后面的所有内容
+const (
+	markerSkip = "<!-- wechat:skip -->"
+	markerEnd  = "<!-- wechat:end -->"
+	markerStop = "<!-- wechat:stop -->"
+)
+
+// filterAction 是过滤器对一个段落或表格的处理结果
+type filterAction int
+
+const (
+	filterKeep filterAction = iota
+	filterDrop
+	filterStop
+)
+
+// sectionFilter 按配置的规则与文档中的标记决定哪些内容需要发布
+type sectionFilter struct {
+	cfg FilterConfig
+	// 当前所在的跳过章节与包含章节，以及开始它们的标题级别
+	skip         *SectionRange
+	skipLevel    int
+	include      *SectionRange
+	includeLevel int
+	// inMarker 为 true 时位于 wechat:skip 与 wechat:end 之间
+	inMarker bool
+}
+
+func newSectionFilter(cfg FilterConfig) *sectionFilter {
+	return &sectionFilter{cfg: cfg}
+}
+
+// paragraph 处理一个命名样式为 style 的段落
+func (f *sectionFilter) paragraph(style, text string) filterAction {
+	text = strings.TrimSpace(text)
+	if f.inMarker {
+		if text == markerEnd {
+			f.inMarker = false
+		}
+		return filterDrop
+	}
+	switch {
+	case text == markerSkip:
+		f.inMarker = true
+		return filterDrop
+	case strings.HasPrefix(text, markerSkip), text == markerEnd:
+		return filterDrop
+	case text == markerStop:
+		return filterStop
+	}
+	for _, stop := range f.cfg.StopAt {
+		if sameHeading(text, stop) {
+			return filterStop
+		}
+	}
+
+	if level := headingLevel(style); level > 0 {
+		f.skip, f.skipLevel = endSection(f.skip, f.skipLevel, text, level)
+		f.include, f.includeLevel = endSection(f.include, f.includeLevel, text, level)
+		if f.skip == nil {
+			f.skip, f.skipLevel = startSection(f.cfg.SkipSections, text, level)
+		}
+		if f.include == nil {
+			f.include, f.includeLevel = startSection(f.cfg.IncludeSections, text, level)
+		}
+	}
+	if !f.keeping() {
+		return filterDrop
+	}
+	for _, s := range f.cfg.SkipStyles {
+		if s == style {
+			return filterDrop
+		}
+	}
+	return filterKeep
+}
+
+// table 处理一个表格，表格跟随所在章节的过滤结果
+func (f *sectionFilter) table() filterAction {
+	if f.inMarker || !f.keeping() {
+		return filterDrop
+	}
+	return filterKeep
+}
+
+func (f *sectionFilter) keeping() bool {
+	if f.skip != nil {
+		return false
+	}
+	return len(f.cfg.IncludeSections) == 0 || f.include != nil
+}
+
+// finish 在文档结束时提示没有结束标记的跳过区域
+func (f *sectionFilter) finish() {
+	if f.inMarker {
+		fmt.Printf("警告: %s 之后没有 %s，后面的内容都已跳过。\n", markerSkip, markerEnd)
+	}
+}
+
+// startSection 返回以该标题开始的章节
+func startSection(ranges []SectionRange, text string, level int) (*SectionRange, int) {
+	for i := range ranges {
+		if sameHeading(text, ranges[i].From) {
+			return &ranges[i], level
+		}
+	}
+	return nil, 0
+}
+
+// endSection 判断标题是否结束当前章节：设置了 To 时遇到该标题结束，否则遇到同级或更高级的标题结束
+func endSection(r *SectionRange, level int, text string, headingLevel int) (*SectionRange, int) {
+	if r == nil {
+		return nil, 0
+	}
+	if r.To != "" {
+		if sameHeading(text, r.To) {
+			return nil, 0
+		}
+		return r, level
+	}
+	if headingLevel <= level {
+		return nil, 0
+	}
+	return r, level
+}
+
+// headingLevel 返回 HEADING_1 到 HEADING_6 的级别，其他样式返回 0
+func headingLevel(style string) int {
+	var level int
+	if _, err := fmt.Sscanf(style, "HEADING_%d", &level); err != nil {
+		return 0
+	}
+	return level
+}
+
+func sameHeading(text, heading string) bool {
+	return strings.EqualFold(strings.TrimSpace(text), strings.TrimSpace(heading))
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// formulaSegment 是按 $...$ 与 $$...$$ 拆分后的一段文字或公式
+type formulaSegment struct {
+	Text    string
+	TeX     string
+	Display bool
+}
+
+// splitFormulas 找出文字中的 LaTeX 公式。规则与 Pandoc 相近：$ 后面紧跟非空白字符，
+// 结束的 $ 前面是非空白字符且后面不是英文字母、数字、下划线或 $，因此 "$5 和 $10" 这样的金额
+// 与 "$PATH:$HOME" 这样的变量不会被当作公式；\$ 表示 $ 本身
+func splitFormulas(text string) []formulaSegment {
+	if !strings.Contains(text, "$") {
+		return []formulaSegment{{Text: text}}
+	}
+	runes := []rune(text)
+	var segments []formulaSegment
+	var sb strings.Builder
+	flush := func() {
+		if sb.Len() > 0 {
+			segments = append(segments, formulaSegment{Text: sb.String()})
+			sb.Reset()
+		}
+	}
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		if r == '\\' && i+1 < len(runes) && runes[i+1] == '$' {
+			sb.WriteRune('$')
+			i++
+			continue
+		}
+		if r != '$' {
+			sb.WriteRune(r)
+			continue
+		}
+		if end := closingDollar(runes, i); end > 0 {
+			display := runes[i+1] == '$'
+			open := 1
+			if display {
+				open = 2
+			}
+			flush()
+			segments = append(segments, formulaSegment{TeX: strings.TrimSpace(string(runes[i+open : end])), Display: display})
+			i = end + open - 1
+			continue
+		}
+		if i+1 < len(runes) && runes[i+1] == '$' {
+			// 没有配对的 $$ 整体作为文字，不拆成 $ 与行内公式
+			sb.WriteString("$$")
+			i++
+			continue
+		}
+		sb.WriteRune(r)
+	}
+	flush()
+	return segments
+}
+
+// closingDollar 返回与 start 处的 $ 或 $$ 配对的结束位置，不是公式时返回 -1
+func closingDollar(runes []rune, start int) int {
+	if start+1 < len(runes) && runes[start+1] == '$' {
+		for j := start + 2; j+1 < len(runes); j++ {
+			if runes[j] == '$' && runes[j+1] == '$' && runes[j-1] != '\\' {
+				if strings.TrimSpace(string(runes[start+2:j])) == "" {
+					return -1
+				}
+				return j
+			}
+		}
+		return -1
+	}
+	if start+1 >= len(runes) || unicode.IsSpace(runes[start+1]) {
+		return -1
+	}
+	for j := start + 1; j < len(runes); j++ {
+		if runes[j] != '$' || runes[j-1] == '\\' {
+			continue
+		}
+		if unicode.IsSpace(runes[j-1]) || (j+1 < len(runes) && (isWordChar(runes[j+1]) || runes[j+1] == '$')) || j == start+1 {
+			return -1
+		}
+		return j
+	}
+	return -1
+}
+
+// isWordChar 判断是否为英文字母、数字或下划线 (代码中变量名使用的字符)
+func isWordChar(r rune) bool {
+	return r == '_' || r < 0x80 && (unicode.IsLetter(r) || unicode.IsDigit(r))
+}
+
+// formulaImage 把公式渲染为图片，图片数据放在 Image.Data 中，之后与文档中的图片一样经过优化与上传
+func formulaImage(tex string, display bool) (*Image, error) {
+	data, formula, err := renderFormula(tex, display)
+	if err != nil {
+		return nil, err
+	}
+	id := "formula-" + sha256Hex([]byte(fmt.Sprintf("%t:%s", display, tex)))[:12]
+	return &Image{
+		ObjectID:    id,
+		URL:         placeholderImageURL(id, ".png"),
+		Alt:         tex,
+		Data:        data,
+		NoWatermark: true,
+		Formula:     formula,
+	}, nil
+}
+
+// expandFormulas 把文本中的公式替换为公式图片，无法渲染的公式保留原文并给出提示
+func (b *docBuilder) expandFormulas(inlines []Inline) []Inline {
+	if !b.cfg.Math.Enabled {
+		return inlines
+	}
+	var out []Inline
+	for _, in := range inlines {
+		t, ok := in.(*Text)
+		if !ok || !strings.Contains(t.Text, "$") {
+			out = append(out, in)
+			continue
+		}
+		for _, seg := range splitFormulas(t.Text) {
+			if seg.TeX == "" {
+				out = append(out, &Text{Text: seg.Text, Style: t.Style})
+				continue
+			}
+			img, err := formulaImage(seg.TeX, seg.Display)
+			if err != nil {
+				fmt.Printf("无法渲染公式 %q: %v\n", seg.TeX, err)
+				delim := "$"
+				if seg.Display {
+					delim = "$$"
+				}
+				out = append(out, &Text{Text: delim + seg.TeX + delim, Style: t.Style})
+				continue
+			}
+			out = append(out, img)
+		}
+	}
+	return mergeTexts(out)
+}
+
+// displayFormula 返回段落中唯一的独立公式 ($$...$$)，段落中还有其他内容时返回 nil
+func displayFormula(inlines []Inline) *Image {
+	var found *Image
+	for _, in := range inlines {
+		if img, ok := in.(*Image); ok && img.Formula != nil && img.Formula.Display && found == nil {
+			found = img
+			continue
+		}
+		if !isBlankInlines([]Inline{in}) {
+			return nil
+		}
+	}
+	return found
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplitFormulas(t *testing.T) {
+	tests := []struct {
+		text string
+		want []formulaSegment
+	}{
+		{"没有公式", []formulaSegment{{Text: "没有公式"}}},
+		{"质能方程 $E=mc^2$。", []formulaSegment{{Text: "质能方程 "}, {TeX: "E=mc^2"}, {Text: "。"}}},
+		{"$$\\sum_i x_i$$", []formulaSegment{{TeX: "\\sum_i x_i", Display: true}}},
+		{"$$ x $$", []formulaSegment{{TeX: "x", Display: true}}},
+		{"$x$的值", []formulaSegment{{TeX: "x"}, {Text: "的值"}}},
+		// 金额
+		{"$5 和 $10", []formulaSegment{{Text: "$5 和 $10"}}},
+		{"售价 $5，折后$3", []formulaSegment{{Text: "售价 $5，折后$3"}}},
+		{"从 $5$10 起", []formulaSegment{{Text: "从 $5$10 起"}}},
+		// 结束的 $ 后面紧跟数字、字母或下划线
+		{"$a$1", []formulaSegment{{Text: "$a$1"}}},
+		{"$PATH:$HOME", []formulaSegment{{Text: "$PATH:$HOME"}}},
+		{"echo $HOME $USER", []formulaSegment{{Text: "echo $HOME $USER"}}},
+		{"$x$_y", []formulaSegment{{Text: "$x$_y"}}},
+		// 转义的 $
+		{"\\$5 与 \\$x\\$", []formulaSegment{{Text: "$5 与 $x$"}}},
+		{"$a\\$b$", []formulaSegment{{TeX: "a\\$b"}}},
+		// 空白与未闭合
+		{"$ x$", []formulaSegment{{Text: "$ x$"}}},
+		{"$x $", []formulaSegment{{Text: "$x $"}}},
+		{"$$", []formulaSegment{{Text: "$$"}}},
+		{"$$ $$", []formulaSegment{{Text: "$$ $$"}}},
+		{"$x", []formulaSegment{{Text: "$x"}}},
+		{"$", []formulaSegment{{Text: "$"}}},
+		// $$ 没有配对的 $$ 时不会回退为行内公式
+		{"$$x$", []formulaSegment{{Text: "$$x$"}}},
+		{"$x$$", []formulaSegment{{Text: "$x$$"}}},
+	}
+	for _, tt := range tests {
+		if got := splitFormulas(tt.text); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("splitFormulas(%q) = %+v, 期望 %+v", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestClosingDollar(t *testing.T) {
+	tests := []struct {
+		text  string
+		start int
+		want  int
+	}{
+		{"$x$", 0, 2},
+		{"$$x$$", 0, 3},
+		{"$$x$", 0, -1},
+		{"a $b$ c", 2, 4},
+		{"$5$6", 0, -1},
+		{"$x\\$$", 0, 4},
+		{"$ x$", 0, -1},
+		{"$x$", 2, -1},
+	}
+	for _, tt := range tests {
+		if got := closingDollar([]rune(tt.text), tt.start); got != tt.want {
+			t.Errorf("closingDollar(%q, %d) = %d, 期望 %d", tt.text, tt.start, got, tt.want)
+		}
+	}
+}
+
+func TestRenderFormulaMalformed(t *testing.T) {
+	for _, tex := range []string{
+		`\frac`, `\frac{1}`, `{`, `}`, `\left(`, `\left( x`, `\left`, `\right)`,
+		`x^`, `x_`, `\sqrt`, `\sqrt[`, `\text{`, `\begin{matrix}`, `\begin{matrix} a \end{pmatrix}`,
+		`\unknown`, `\`, ``,
+	} {
+		func() {
+			defer func() {
+				if r := recover(); r != nil {
+					t.Errorf("renderFormula(%q) panic: %v", tex, r)
+				}
+			}()
+			if _, _, err := renderFormula(tex, false); err == nil {
+				t.Errorf("renderFormula(%q) 应返回错误", tex)
+			}
+		}()
+	}
+}
+
+func TestRenderFormula(t *testing.T) {
+	for _, tt := range []struct {
+		tex     string
+		display bool
+	}{
+		{`E=mc^2`, false},
+		{`\frac{a}{b}`, true},
+		{`\left( \sum_{i=1}^n x_i \right)`, true},
+	} {
+		data, formula, err := renderFormula(tt.tex, tt.display)
+		if err != nil {
+			t.Errorf("renderFormula(%q) 出错: %v", tt.tex, err)
+			continue
+		}
+		if len(data) == 0 || formula == nil || formula.Display != tt.display {
+			t.Errorf("renderFormula(%q) = %d 字节, %+v", tt.tex, len(data), formula)
+		}
+	}
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/gif"
+	_ "image/jpeg"
+	"io"
+	"math"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/image/draw"
+	"golang.org/x/image/math/f64"
+	_ "golang.org/x/image/webp"
+)
+
+// imagePipeline 下载文档中的图片，按 Google Docs 中的裁剪与旋转设置处理后保存到本地目录
+type imagePipeline struct {
+	client *http.Client
+	dir    string
+	cfg    ImageConfig
+	// uploader 为 nil 时只保存到本地，图片地址保留为占位符
+	uploader ImageUploader
+	prefix   string
+	// cache 为 nil 时每次都重新上传
+	cache *imageCache
+	// watermark 为 nil 时不加水印
+	watermark *watermarker
+}
+
+func newImagePipeline(client *http.Client, dir string, cfg ImageConfig, uploader ImageUploader, prefix string, cache *imageCache) (*imagePipeline, error) {
+	watermark, err := newWatermarker(cfg.Watermark)
+	if err != nil {
+		return nil, err
+	}
+	return &imagePipeline{client: client, dir: dir, cfg: cfg, uploader: uploader, prefix: prefix, cache: cache, watermark: watermark}, nil
+}
+
+// process 处理文档中的所有图片，单张图片失败时保留占位符并继续处理其余图片
+func (p *imagePipeline) process(ctx context.Context, d *Document) error {
+	if err := os.MkdirAll(p.dir, 0755); err != nil {
+		return fmt.Errorf("无法创建图片目录: %v", err)
+	}
+	forEachImage(d, func(img *Image) {
+		if err := p.processImage(ctx, img); err != nil {
+			fmt.Printf("处理图片 %s 失败: %v\n", img.ObjectID, err)
+		}
+	})
+	if p.cache != nil {
+		return p.cache.save()
+	}
+	return nil
+}
+
+func (p *imagePipeline) processImage(ctx context.Context, img *Image) error {
+	if (img.SourceURL == "" && img.Data == nil) || img.LocalPath != "" {
+		return nil
+	}
+	data := img.Data
+	if data == nil {
+		var err error
+		if data, err = p.download(ctx, img.SourceURL); err != nil {
+			return err
+		}
+	}
+	src, format, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("无法解码图片: %v", err)
+	}
+
+	if !img.Crop.isZero() || img.Rotation != 0 {
+		src = rotateImage(cropImage(src, img.Crop), img.Rotation+img.Crop.Angle)
+	}
+	if p.watermark != nil && !img.NoWatermark {
+		if src, err = p.watermark.apply(src); err != nil {
+			return err
+		}
+	}
+	original := len(data)
+	data, ext, size, err := optimizeImage(src, format, p.cfg)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("图片 %s: %s (%s) → %s (%s, %dx%d)\n", img.ObjectID,
+		formatBytes(original), format, formatBytes(len(data)), strings.TrimPrefix(ext, "."), size.X, size.Y)
+
+	path := filepath.Join(p.dir, "image-for-"+img.ObjectID+ext)
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("无法保存图片: %v", err)
+	}
+	img.LocalPath = path
+	img.URL = placeholderImageURL(img.ObjectID, ext)
+
+	if p.uploader != nil {
+		url, err := p.upload(ctx, data, ext)
+		if err != nil {
+			return err
+		}
+		img.URL = url
+	}
+	return nil
+}
+
+// upload 上传图片，缓存中已有同一内容在同一图床上的地址时直接使用
+func (p *imagePipeline) upload(ctx context.Context, data []byte, ext string) (string, error) {
+	hash := sha256Hex(data)
+	key := imageObjectKey(p.prefix, data, ext)
+	target := p.uploader.Target()
+	if p.cache != nil {
+		if url, ok := p.cache.lookup(hash, target, key); ok {
+			fmt.Printf("    使用缓存: %s\n", url)
+			return url, nil
+		}
+	}
+	url, err := p.uploader.Upload(ctx, key, data, imageContentType(ext))
+	if err != nil {
+		return "", err
+	}
+	fmt.Printf("    已上传: %s\n", url)
+	if p.cache != nil {
+		p.cache.store(hash, len(data), ext, target, key, url)
+	}
+	return url, nil
+}
+
+func (p *imagePipeline) download(ctx context.Context, url string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("无法下载图片: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusForbidden && strings.HasPrefix(url, driveExportURL) {
+		// 没有启用 Drive API，或 token_drive.json 中的授权已失效
+		return nil, fmt.Errorf("导出绘图失败: %s，请确认已启用 Drive API，删除 token_drive.json 后重新授权", resp.Status)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("下载图片失败: %s", resp.Status)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// cropImage 按各边的偏移比例裁剪图片。负的偏移 (向外扩展) 在网页上没有意义，按 0 处理
+func cropImage(src image.Image, crop ImageCrop) image.Image {
+	clamp := func(v float64) float64 { return math.Max(0, math.Min(1, v)) }
+	b := src.Bounds()
+	w, h := float64(b.Dx()), float64(b.Dy())
+	rect := image.Rect(
+		b.Min.X+int(math.Round(clamp(crop.Left)*w)),
+		b.Min.Y+int(math.Round(clamp(crop.Top)*h)),
+		b.Max.X-int(math.Round(clamp(crop.Right)*w)),
+		b.Max.Y-int(math.Round(clamp(crop.Bottom)*h)),
+	)
+	if rect.Empty() {
+		return src
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, rect.Dx(), rect.Dy()))
+	draw.Draw(dst, dst.Bounds(), src, rect.Min, draw.Src)
+	return dst
+}
+
+// rotateImage 把图片绕中心顺时针旋转 angle 弧度，画布扩大到能容纳整张图片，空白处透明
+func rotateImage(src image.Image, angle float64) image.Image {
+	// 去掉整圈，接近 0 时不旋转
+	angle = math.Mod(angle, 2*math.Pi)
+	if math.Abs(angle) < 1e-6 {
+		return src
+	}
+	b := src.Bounds()
+	w, h := float64(b.Dx()), float64(b.Dy())
+	sin, cos := math.Sincos(angle)
+	nw := math.Ceil(math.Abs(w*cos) + math.Abs(h*sin) - 1e-6)
+	nh := math.Ceil(math.Abs(w*sin) + math.Abs(h*cos) - 1e-6)
+	dst := image.NewRGBA(image.Rect(0, 0, int(nw), int(nh)))
+	draw.Draw(dst, dst.Bounds(), image.NewUniform(color.Transparent), image.Point{}, draw.Src)
+
+	// 源图中心移到原点，旋转 (y 轴向下，因此该矩阵为顺时针)，再移到新画布中心
+	cx, cy := float64(b.Min.X)+w/2, float64(b.Min.Y)+h/2
+	m := f64.Aff3{
+		cos, -sin, nw/2 - cos*cx + sin*cy,
+		sin, cos, nh/2 - sin*cx - cos*cy,
+	}
+	draw.CatmullRom.Transform(dst, m, src, b, draw.Over, nil)
+	return dst
+}
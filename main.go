@@ -22,6 +22,7 @@ import (
 	"golang.org/x/oauth2"
 	"golang.org/x/oauth2/google"
 	"google.golang.org/api/docs/v1"
+	"google.golang.org/api/drive/v3"
 	"google.golang.org/api/option"
 )
 
@@ -34,6 +35,8 @@ const (
 	colorHeaderText   = "#ffffff" // 白色 (用于深色背景标题)
 	colorMuted        = "#6c757d" // 辅助/静音颜色 (用于标签)
 	colorBorder       = "#dee2e6" // 边框颜色
+	colorAccent       = "#e03131" // 强调红 (用于文字颜色映射)
+	colorHighlight    = "#fff3bf" // 高亮黄 (用于背景高亮映射)
 
 	// --- 基础与布局 ---
 	styleBody = `padding: 16px 20px; font-family: -apple-system, BlinkMacSystemFont, 'Helvetica Neue', 'PingFang SC', 'Microsoft YaHei', sans-serif; letter-spacing: 0.544px; font-size: 16px; line-height: 1.8; color: ` + colorText + `;`
@@ -47,12 +50,42 @@ const (
 	styleParagraph  = `margin-top: 1.2em; margin-bottom: 1.2em;`
 	styleBlockquote = `padding: 15px 20px; margin: 25px 0; background-color: ` + colorPrimaryLight + `; border-left: 4px solid ` + colorPrimary + `; color: #053b84; font-size: 15px;`
 	styleCodeBlock  = `display: block; overflow-x: auto; padding: 1.2em; background: #282c34; color: #abb2bf; margin: 25px 0; border-radius: 8px; font-family: 'SFMono-Regular', Consolas, 'Liberation Mono', Menlo, Courier, monospace; font-size: 14px; line-height: 1.5;`
-	styleImage      = `max-width: 100%; height: auto; display: block; margin: 25px auto; border-radius: 8px; box-shadow: 0 8px 20px rgba(0,0,0,0.12);`
+	styleHighlight  = `padding: 0 2px; border-radius: 2px;` // 背景高亮文字的附加样式
+	styleUnderline  = `text-decoration: underline; text-underline-offset: 3px;`
+	styleSmallCaps  = `font-variant: small-caps;`
+	// 上下标使用较小字号，并把行高置零以免撑开行距
+	styleSuperscript   = `font-size: 75%; line-height: 0; vertical-align: super;`
+	styleSubscript     = `font-size: 75%; line-height: 0; vertical-align: sub;`
+	styleFigure        = `margin: 25px 0; text-align: center;`
+	styleFigureImage   = `max-width: 100%; height: auto; display: block; margin: 0 auto; border-radius: 8px; box-shadow: 0 8px 20px rgba(0,0,0,0.12);`
+	styleFigureCaption = `margin: 12px 0 0; font-size: 14px; line-height: 1.6; color: ` + colorMuted + `; text-align: center;`
+	styleFigureLabel   = `margin-right: 6px; font-weight: bold; color: ` + colorPrimary + `;`
+	styleImage         = `max-width: 100%; height: auto; display: block; margin: 25px auto; border-radius: 8px; box-shadow: 0 8px 20px rgba(0,0,0,0.12);`
+
+	// --- 公式 (按 em 设置尺寸，随正文字号缩放) ---
+	styleFormulaInline  = `display: inline-block; max-width: none; margin: 0 2px;`
+	styleFormulaDisplay = `display: block; max-width: 100%; height: auto; margin: 0 auto;`
+
+	// --- 提示框 (边框与背景颜色按类型取自 calloutColors) ---
+	styleCallout          = `margin: 25px 0; padding: 14px 18px; border-radius: 8px; font-size: 15px;`
+	styleCalloutTitle     = `margin: 0 0 6px; font-weight: bold;`
+	styleCalloutIcon      = `margin-right: 6px;`
+	styleCalloutParagraph = `margin: 0.6em 0;`
+
+	// --- 分隔线 (两段渐变细线夹着一个主题色圆点) ---
+	styleDivider      = `margin: 36px 0; text-align: center; line-height: 0;`
+	styleDividerLeft  = `display: inline-block; width: 30%; height: 1px; vertical-align: middle; background: linear-gradient(to right, transparent, ` + colorPrimary + `);`
+	styleDividerRight = `display: inline-block; width: 30%; height: 1px; vertical-align: middle; background: linear-gradient(to left, transparent, ` + colorPrimary + `);`
+	styleDividerDot   = `display: inline-block; width: 6px; height: 6px; margin: 0 12px; vertical-align: middle; border-radius: 50%; background-color: ` + colorPrimary + `;`
 
 	// --- 列表 ---
 	styleUnorderedList = `margin: 1.2em 0; padding-left: 25px; list-style-type: disc;`
 	styleOrderedList   = `margin: 1.2em 0; padding-left: 25px;`
 	styleListItem      = `margin-bottom: 0.8em;`
+	// 微信会去掉 <input>，核对清单的复选框用 ☑/☐ 字符显示
+	styleChecklist     = `margin: 1.2em 0; padding-left: 1.2em; list-style-type: none;`
+	styleTaskChecked   = `margin-right: 6px; color: ` + colorPrimary + `;`
+	styleTaskUnchecked = `margin-right: 6px; color: ` + colorMuted + `;`
 
 	// --- 表格卡片化样式 ---
 	styleTableWrapper = `margin: 30px 0;`
@@ -66,6 +99,12 @@ const (
 
 	// 标签部分的样式 (例如 "职位: ")
 	styleDataLabel = `font-weight: 600; color: ` + colorText + `; margin-right: 8px;`
+
+	// --- 脚注 ---
+	styleFootnoteRef     = `font-size: 12px; color: ` + colorPrimary + `; line-height: 0;`
+	styleFootnoteSection = `margin: 40px 0 0; padding-top: 15px; border-top: 1px solid ` + colorBorder + `; font-size: 13px; color: ` + colorMuted + `;`
+	styleFootnoteTitle   = `margin: 0 0 10px; font-size: 14px; font-weight: bold; color: ` + colorText + `;`
+	styleFootnoteItem    = `margin: 0 0 6px; line-height: 1.6;`
 )
 
 // CSS Keyframes 动画定义 (保持不变)
@@ -84,10 +123,68 @@ const cssKeyframes = `
 </style>
 `
 
+// calloutColors 是各类提示框的主色 (边框与标题) 和背景色
+var calloutColors = map[string]struct{ Border, Background string }{
+	"note":      {colorPrimary, colorPrimaryLight},
+	"tip":       {"#2f9e44", "#ebfbee"},
+	"important": {"#7048e8", "#f3f0ff"},
+	"warning":   {"#f08c00", "#fff9db"},
+	"caution":   {colorAccent, "#fff5f5"},
+}
+
 // --- 样式配置结束 ---
 
-func getClient(ctx context.Context, config *oauth2.Config) *http.Client {
-	tokFile := "token.json"
+// newDocsService 读取 credentials.json 并完成授权，返回 Docs 服务以及带授权的 HTTP 客户端 (用于下载图片)。
+// 这里只申请 Docs 只读权限，导出绘图所需的 Drive 权限由 newDriveClient 在需要时单独申请
+func newDocsService(ctx context.Context, proxyAddr string) (*docs.Service, *http.Client, error) {
+	client, err := newOAuthClient(ctx, proxyAddr, "token.json", docs.DocumentsReadonlyScope)
+	if err != nil {
+		return nil, nil, err
+	}
+	srv, err := docs.NewService(ctx, option.WithHTTPClient(client))
+	if err != nil {
+		return nil, nil, fmt.Errorf("无法创建 Docs 服务: %v", err)
+	}
+	return srv, client, nil
+}
+
+// newDriveClient 返回同时带有 Docs 与 Drive 只读权限的 HTTP 客户端，只在文章中有需要导出的绘图时调用。
+// Drive 只读权限可以读取整个 Drive，因此单独保存在 token_drive.json 中，不影响只转换普通文档时的授权
+func newDriveClient(ctx context.Context, proxyAddr string) (*http.Client, error) {
+	fmt.Println("文档中有 Google 绘图，导出绘图需要 Drive 只读权限")
+	return newOAuthClient(ctx, proxyAddr, "token_drive.json", docs.DocumentsReadonlyScope, drive.DriveReadonlyScope)
+}
+
+// newOAuthClient 读取 credentials.json，使用 tokFile 中保存的授权 (没有时在浏览器中授权) 创建 HTTP 客户端
+func newOAuthClient(ctx context.Context, proxyAddr, tokFile string, scopes ...string) (*http.Client, error) {
+	b, err := os.ReadFile("credentials.json")
+	if err != nil {
+		return nil, fmt.Errorf("无法读取客户端密钥文件 (credentials.json): %v", err)
+	}
+
+	config, err := google.ConfigFromJSON(b, scopes...)
+	if err != nil {
+		return nil, fmt.Errorf("无法解析客户端密钥文件为配置: %v", err)
+	}
+
+	if proxyAddr != "" {
+		fmt.Printf("使用 SOCKS5 代理: %s\n", proxyAddr)
+		dialer, err := proxy.SOCKS5("tcp", proxyAddr, nil, proxy.Direct)
+		if err != nil {
+			return nil, fmt.Errorf("无法创建 SOCKS5 代理拨号器: %v", err)
+		}
+
+		httpTransport := &http.Transport{}
+		httpTransport.DialContext = dialer.(proxy.ContextDialer).DialContext
+
+		httpClient := &http.Client{Transport: httpTransport}
+		ctx = context.WithValue(ctx, oauth2.HTTPClient, httpClient)
+	}
+
+	return getClient(ctx, config, tokFile), nil
+}
+
+func getClient(ctx context.Context, config *oauth2.Config, tokFile string) *http.Client {
 	tok, err := tokenFromFile(tokFile)
 	if err != nil {
 		tok = getTokenFromWeb(ctx, config)
@@ -134,146 +231,37 @@ func saveToken(path string, token *oauth2.Token) {
 	json.NewEncoder(f).Encode(token)
 }
 
-// processDocument 是核心处理函数，增加了忽略标题、参考文献以及处理表格的功能
-func processDocument(srv *docs.Service, docId string) (string, error) {
-	doc, err := srv.Documents.Get(docId).Do()
+// processDocument 是核心处理函数，获取文档并为选中的每个标签页构建中间文档模型 (忽略标题、参考文献，处理列表、表格与脚注)。
+// tab 按 ID 或标题选择标签页，allTabs 为 true 时转换所有标签页，都为空时只转换第一个标签页。
+// 标签页中有分割点 (命名范围 cut) 时，每一部分是一篇文章
+func processDocument(srv *docs.Service, docId string, cfg *Config, tab string, allTabs bool) ([]*Document, error) {
+	doc, err := srv.Documents.Get(docId).
+		IncludeTabsContent(true).
+		SuggestionsViewMode(suggestionsViewModes[cfg.Suggestions]).
+		Do()
 	if err != nil {
-		return "", fmt.Errorf("无法获取文档: %v", err)
+		return nil, fmt.Errorf("无法获取文档: %v", err)
 	}
-
-	var markdownBuilder strings.Builder
-
-	// 定义需要忽略的章节标题
-	stopHeadings := map[string]bool{
-		"参考文献":       true,
-		"引用的文献":      true,
-		"References": true, // 也可加入英文
+	tabs, err := selectTabs(documentTabs(doc), tab, allTabs)
+	if err != nil {
+		return nil, err
 	}
-
-	// 遍历文档内容元素
-	for _, content := range doc.Body.Content {
-		// --- 1. 处理段落 (Paragraph) ---
-		if content.Paragraph != nil {
-			para := content.Paragraph
-
-			if para.ParagraphStyle.NamedStyleType == "TITLE" {
-				fmt.Println("已忽略文档标题。")
-				continue
-			}
-
-			var paraTextBuilder strings.Builder
-			for _, elem := range para.Elements {
-				if elem.TextRun != nil {
-					paraTextBuilder.WriteString(elem.TextRun.Content)
-				}
-			}
-			paraText := strings.TrimSpace(paraTextBuilder.String())
-
-			if stopHeadings[paraText] {
-				fmt.Printf("\n检测到章节 “%s”，已停止后续内容转换。\n", paraText)
-				break
-			}
-
-			isHeading := false
-			switch para.ParagraphStyle.NamedStyleType {
-			case "HEADING_1":
-				markdownBuilder.WriteString("# ")
-				isHeading = true
-			case "HEADING_2":
-				markdownBuilder.WriteString("## ")
-				isHeading = true
-			case "HEADING_3":
-				markdownBuilder.WriteString("### ")
-				isHeading = true
-			}
-
-			if para.Bullet != nil {
-				nestingLevel := 0
-				if para.Bullet.NestingLevel > 0 {
-					nestingLevel = int(para.Bullet.NestingLevel)
-				}
-				markdownBuilder.WriteString(strings.Repeat("  ", nestingLevel))
-				markdownBuilder.WriteString("* ")
-			}
-
-			for _, elem := range para.Elements {
-				if elem.TextRun != nil {
-					text := elem.TextRun.Content
-					style := elem.TextRun.TextStyle
-					if isHeading && text == "\n" {
-						continue
-					}
-					// 移除Markdown表格中不应存在的换行符
-					text = strings.ReplaceAll(text, "\n", "")
-					if style.Bold {
-						text = "**" + text + "**"
-					}
-					if style.Italic {
-						text = "*" + text + "*"
-					}
-					if style.Strikethrough {
-						text = "~~" + text + "~~"
-					}
-					if style.Link != nil && style.Link.Url != "" {
-						text = fmt.Sprintf("[%s](%s)", text, style.Link.Url)
-					}
-					markdownBuilder.WriteString(text)
-				} else if elem.InlineObjectElement != nil {
-					objId := elem.InlineObjectElement.InlineObjectId
-					if inlineObj, ok := doc.InlineObjects[objId]; ok {
-						_ = inlineObj.InlineObjectProperties.EmbeddedObject.ImageProperties
-						placeholderURL := fmt.Sprintf("https://your-cdn.com/path/to/image-for-%s.png", objId)
-						markdownBuilder.WriteString(fmt.Sprintf("\n\n![Image from Google Docs](%s)\n\n", placeholderURL))
-					}
-				}
-			}
-
-			if !isHeading {
-				markdownBuilder.WriteString("\n")
-			}
-			markdownBuilder.WriteString("\n")
-		} else if content.Table != nil { // --- 2. 新增：处理表格 (Table) ---
-			table := content.Table
-			if len(table.TableRows) > 0 {
-				// 遍历行
-				for i, row := range table.TableRows {
-					var rowContent []string
-					// 遍历单元格
-					for _, cell := range row.TableCells {
-						var cellTextBuilder strings.Builder
-						// 提取单元格内的文本
-						for _, cellContent := range cell.Content {
-							if cellContent.Paragraph != nil {
-								for _, paraElement := range cellContent.Paragraph.Elements {
-									if paraElement.TextRun != nil {
-										// 移除单元格文本中的换行符，防止破坏 Markdown 表格结构
-										text := strings.ReplaceAll(paraElement.TextRun.Content, "\n", "")
-										cellTextBuilder.WriteString(text)
-									}
-								}
-							}
-						}
-						rowContent = append(rowContent, cellTextBuilder.String())
-					}
-
-					// 构建 Markdown 表格行
-					markdownBuilder.WriteString("| " + strings.Join(rowContent, " | ") + " |\n")
-
-					// 如果是第一行（表头），则在下面添加分隔线
-					if i == 0 {
-						var headerSeparators []string
-						for range row.TableCells {
-							headerSeparators = append(headerSeparators, "---")
-						}
-						markdownBuilder.WriteString("| " + strings.Join(headerSeparators, " | ") + " |\n")
-					}
-				}
-				markdownBuilder.WriteString("\n") // 表格结束后添加一个换行符
-			}
+	if cfg.Suggestions == "fail" {
+		if found := findSuggestions(tabs); len(found) > 0 {
+			return nil, suggestionsError(found)
 		}
 	}
-
-	return markdownBuilder.String(), nil
+	var articles []*Document
+	for _, t := range tabs {
+		if len(tabs) > 1 {
+			fmt.Printf("\n--- 标签页: %s ---\n", t.Title)
+		}
+		for _, article := range buildDocument(t.Doc, cfg) {
+			article.TabID, article.TabTitle = t.ID, t.Title
+			articles = append(articles, article)
+		}
+	}
+	return articles, nil
 }
 
 type wechatHTMLRenderer struct {
@@ -289,13 +277,24 @@ func (r *wechatHTMLRenderer) RegisterFuncs(reg renderer.NodeRendererFuncRegister
 	reg.Register(ast.KindCodeBlock, r.renderCodeBlock)
 	reg.Register(ast.KindFencedCodeBlock, r.renderCodeBlock)
 	reg.Register(ast.KindImage, r.renderImage)
+	reg.Register(ast.KindThematicBreak, r.renderThematicBreak)
 	reg.Register(ast.KindList, r.renderList)
 	reg.Register(ast.KindListItem, r.renderListItem)
+	reg.Register(ext_ast.KindTaskCheckBox, r.renderTaskCheckBox)
 	// Table renderer
 	reg.Register(ext_ast.KindTable, r.renderTable)
 	reg.Register(ext_ast.KindTableHeader, r.renderTableHeader)
 	reg.Register(ext_ast.KindTableRow, r.renderTableRow)
 	reg.Register(ext_ast.KindTableCell, r.renderTableCell)
+	// Footnote renderer
+	reg.Register(ext_ast.KindFootnoteLink, r.renderFootnoteLink)
+	reg.Register(ext_ast.KindFootnoteBacklink, r.renderFootnoteBacklink)
+	reg.Register(ext_ast.KindFootnoteList, r.renderFootnoteList)
+	reg.Register(ext_ast.KindFootnote, r.renderFootnote)
+	reg.Register(kindStyledSpan, r.renderStyledSpan)
+	reg.Register(kindCallout, r.renderCallout)
+	reg.Register(kindFigure, r.renderFigure)
+	reg.Register(kindFigureCaption, r.renderFigureCaption)
 }
 
 // renderHeading 不再生成 h 标签，而是生成带有标题样式的 p 标签，以兼容微信编辑器
@@ -321,20 +320,40 @@ func (r *wechatHTMLRenderer) renderHeading(w util.BufWriter, source []byte, node
 }
 
 func (r *wechatHTMLRenderer) renderParagraph(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	// 列表项和脚注中的段落由父节点负责包裹
+	switch node.Parent().(type) {
+	case *ast.ListItem, *ext_ast.Footnote:
+		return ast.WalkContinue, nil
+	}
 	if entering {
-		if _, ok := node.Parent().(*ast.ListItem); ok {
-			return ast.WalkContinue, nil
+		style := styleParagraph
+		if _, ok := node.Parent().(*calloutNode); ok {
+			style = styleCalloutParagraph
 		}
-		_, _ = w.WriteString(fmt.Sprintf("<p style=\"%s\">", styleParagraph))
-	} else {
-		if _, ok := node.Parent().(*ast.ListItem); ok {
-			return ast.WalkContinue, nil
+		if extra, ok := node.AttributeString("style"); ok {
+			style += " " + string(extra.([]byte))
 		}
+		_, _ = w.WriteString(fmt.Sprintf("<p style=\"%s\">", style))
+	} else {
 		_, _ = w.WriteString("</p>\n")
 	}
 	return ast.WalkContinue, nil
 }
 
+// renderCallout 渲染提示框：带类型图标的标题，内容放在按类型着色的卡片中
+func (r *wechatHTMLRenderer) renderCallout(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	n := node.(*calloutNode)
+	if entering {
+		colors := calloutColors[n.Variant]
+		_, _ = w.WriteString(fmt.Sprintf("<section style=\"%s border-left: 4px solid %s; background-color: %s;\">", styleCallout, colors.Border, colors.Background))
+		_, _ = w.WriteString(fmt.Sprintf("<p style=\"%s color: %s;\"><span style=\"%s\">%s</span>%s</p>", styleCalloutTitle, colors.Border,
+			styleCalloutIcon, calloutKinds[n.Variant].Icon, util.EscapeHTML([]byte(n.Title))))
+	} else {
+		_, _ = w.WriteString("</section>\n")
+	}
+	return ast.WalkContinue, nil
+}
+
 func (r *wechatHTMLRenderer) renderBlockquote(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
 	if entering {
 		_, _ = w.WriteString(fmt.Sprintf("<blockquote style=\"%s\">", styleBlockquote))
@@ -372,11 +391,62 @@ func (r *wechatHTMLRenderer) renderCodeBlock(w util.BufWriter, source []byte, no
 func (r *wechatHTMLRenderer) renderImage(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
 	n := node.(*ast.Image)
 	if entering {
-		_, _ = w.WriteString(fmt.Sprintf("<img src=\"%s\" alt=\"%s\" style=\"%s\" />", n.Destination, n.Text(source), styleImage))
+		style := styleImage
+		_, inFigure := n.Parent().(*figureNode)
+		if inFigure {
+			style = styleFigureImage
+		}
+		if width, ok := n.AttributeString("width"); ok {
+			style += fmt.Sprintf(" width: %s;", width)
+		}
+		if height, ok := n.AttributeString("height"); ok {
+			width, _ := n.AttributeString("width")
+			depth, _ := n.AttributeString("depth")
+			if inFigure {
+				style = fmt.Sprintf("%s width: %s;", styleFormulaDisplay, width)
+			} else {
+				style = fmt.Sprintf("%s width: %s; height: %s; vertical-align: -%s;", styleFormulaInline, width, height, depth)
+			}
+		}
+		alt := util.EscapeHTML(nodeText(n, source))
+		_, _ = w.WriteString(fmt.Sprintf("<img src=\"%s\" alt=\"%s\" style=\"%s\" />", util.EscapeHTML(n.Destination), alt, style))
 	}
 	return ast.WalkSkipChildren, nil
 }
 
+// renderThematicBreak 把分隔线渲染为主题色的装饰线
+func (r *wechatHTMLRenderer) renderThematicBreak(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	if entering {
+		_, _ = w.WriteString(fmt.Sprintf("<section style=\"%s\"><span style=\"%s\"></span><span style=\"%s\"></span><span style=\"%s\"></span></section>\n",
+			styleDivider, styleDividerLeft, styleDividerDot, styleDividerRight))
+	}
+	return ast.WalkContinue, nil
+}
+
+// renderFigure 把单独成段的图片及其说明包裹在 section 中
+func (r *wechatHTMLRenderer) renderFigure(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	if entering {
+		_, _ = w.WriteString(fmt.Sprintf("<section style=\"%s\">", styleFigure))
+	} else {
+		_, _ = w.WriteString("</section>\n")
+	}
+	return ast.WalkContinue, nil
+}
+
+// renderFigureCaption 渲染图片说明，编号使用主题色突出显示
+func (r *wechatHTMLRenderer) renderFigureCaption(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	n := node.(*figureCaptionNode)
+	if entering {
+		_, _ = w.WriteString(fmt.Sprintf("<p style=\"%s\">", styleFigureCaption))
+		if n.Label != "" {
+			_, _ = w.WriteString(fmt.Sprintf("<span style=\"%s\">%s</span>", styleFigureLabel, util.EscapeHTML([]byte(n.Label))))
+		}
+	} else {
+		_, _ = w.WriteString("</p>")
+	}
+	return ast.WalkContinue, nil
+}
+
 func (r *wechatHTMLRenderer) renderList(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
 	n := node.(*ast.List)
 	tag := "ul"
@@ -385,6 +455,9 @@ func (r *wechatHTMLRenderer) renderList(w util.BufWriter, source []byte, node as
 		tag = "ol"
 		style = styleOrderedList
 	}
+	if _, ok := n.AttributeString("checklist"); ok {
+		style = styleChecklist
+	}
 	if entering {
 		_, _ = w.WriteString(fmt.Sprintf("<%s style=\"%s\">\n", tag, style))
 	} else {
@@ -402,6 +475,19 @@ func (r *wechatHTMLRenderer) renderListItem(w util.BufWriter, source []byte, nod
 	return ast.WalkContinue, nil
 }
 
+// renderTaskCheckBox 用 ☑/☐ 字符代替复选框
+func (r *wechatHTMLRenderer) renderTaskCheckBox(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	if !entering {
+		return ast.WalkContinue, nil
+	}
+	if node.(*ext_ast.TaskCheckBox).IsChecked {
+		_, _ = w.WriteString(fmt.Sprintf("<span style=\"%s\">☑</span>", styleTaskChecked))
+	} else {
+		_, _ = w.WriteString(fmt.Sprintf("<span style=\"%s\">☐</span>", styleTaskUnchecked))
+	}
+	return ast.WalkContinue, nil
+}
+
 // renderTable 初始化表格渲染，注入CSS动画
 // renderTable 初始化表格渲染，重置所有状态
 func (r *wechatHTMLRenderer) renderTable(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
@@ -446,76 +532,124 @@ func (r *wechatHTMLRenderer) renderTableRow(w util.BufWriter, source []byte, nod
 	return ast.WalkContinue, nil
 }
 
-// renderTableCell 使用新的状态旗帜进行判断
+// renderTableCell 使用新的状态旗帜进行判断。表头单元格只记录文字，
+// 内容单元格的子节点按正常的行内元素渲染 (保留强调、链接、图片与脚注引用)
 func (r *wechatHTMLRenderer) renderTableCell(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
 	n := node.(*ext_ast.TableCell)
+	if r.inTableHeader {
+		if entering {
+			// 如果我们正处于表头区域，将单元格文本 (包含排版处理后插入的 String 节点) 存入切片
+			r.tableHeaders = append(r.tableHeaders, strings.TrimSpace(string(nodeText(n, source))))
+		}
+		return ast.WalkSkipChildren, nil
+	}
+	if !hasCellContent(n, source) {
+		return ast.WalkSkipChildren, nil
+	}
 	if !entering {
+		_, _ = w.WriteString("</p>\n")
 		return ast.WalkContinue, nil
 	}
+	cellIndex := 0
+	for p := n.PreviousSibling(); p != nil; p = p.PreviousSibling() {
+		cellIndex++
+	}
 
-	// 提取单元格文本的逻辑保持不变
-	var cellTextBuilder strings.Builder
-	_ = ast.Walk(n, func(child ast.Node, entering bool) (ast.WalkStatus, error) {
-		if textNode, ok := child.(*ast.Text); ok && entering {
-			cellTextBuilder.Write(textNode.Segment.Value(source))
-		}
-		return ast.WalkContinue, nil
-	})
-	cellText := strings.TrimSpace(cellTextBuilder.String())
+	headerLabel := ""
+	if cellIndex < len(r.tableHeaders) {
+		headerLabel = r.tableHeaders[cellIndex]
+	}
 
-	// 【核心修正】用简单的布尔值检查，替代之前脆弱的父节点检查
-	if r.inTableHeader {
-		// 如果我们正处于表头区域，将单元格文本存入切片
-		r.tableHeaders = append(r.tableHeaders, cellText)
-	} else {
-		// 否则，我们就在内容区域
-		if cellText == "" {
-			return ast.WalkSkipChildren, nil
+	isLast := true
+	for p := n.NextSibling(); p != nil; p = p.NextSibling() {
+		if hasCellContent(p, source) {
+			isLast = false
+			break
 		}
+	}
 
-		cellIndex := 0
-		for p := n.PreviousSibling(); p != nil; p = p.PreviousSibling() {
-			cellIndex++
-		}
+	rowStyle := styleDataRow
+	if isLast {
+		rowStyle = styleDataRowLast
+	}
 
-		headerLabel := ""
-		if cellIndex < len(r.tableHeaders) {
-			headerLabel = r.tableHeaders[cellIndex]
-		}
+	_, _ = w.WriteString(fmt.Sprintf("<p style=\"%s\">", rowStyle))
+	_, _ = w.WriteString(fmt.Sprintf("<strong style=\"%s\">%s: </strong>", styleDataLabel, util.EscapeHTML([]byte(headerLabel))))
 
-		isLast := true
-		for p := n.NextSibling(); p != nil; p = p.NextSibling() {
-			var nextCellTextBuilder strings.Builder
-			_ = ast.Walk(p, func(child ast.Node, entering bool) (ast.WalkStatus, error) {
-				if textNode, ok := child.(*ast.Text); ok && entering {
-					nextCellTextBuilder.Write(textNode.Segment.Value(source))
-				}
-				return ast.WalkContinue, nil
-			})
-			if strings.TrimSpace(nextCellTextBuilder.String()) != "" {
-				isLast = false
-				break
-			}
-		}
+	return ast.WalkContinue, nil
+}
 
-		rowStyle := styleDataRow
-		if isLast {
-			rowStyle = styleDataRowLast
+// hasCellContent 判断单元格中是否有需要显示的内容：文字、图片或脚注引用
+func hasCellContent(n ast.Node, source []byte) bool {
+	if strings.TrimSpace(string(nodeText(n, source))) != "" {
+		return true
+	}
+	found := false
+	_ = ast.Walk(n, func(c ast.Node, entering bool) (ast.WalkStatus, error) {
+		switch c.(type) {
+		case *ast.Image, *ext_ast.FootnoteLink:
+			found = true
+			return ast.WalkStop, nil
 		}
+		return ast.WalkContinue, nil
+	})
+	return found
+}
+
+// renderFootnoteLink 微信会移除页内锚点，脚注引用只渲染为上标编号
+func (r *wechatHTMLRenderer) renderFootnoteLink(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	n := node.(*ext_ast.FootnoteLink)
+	if entering {
+		_, _ = w.WriteString(fmt.Sprintf("<sup style=\"%s\">[%d]</sup>", styleFootnoteRef, n.Index))
+	}
+	return ast.WalkContinue, nil
+}
+
+func (r *wechatHTMLRenderer) renderFootnoteBacklink(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	return ast.WalkContinue, nil
+}
 
-		_, _ = w.WriteString(fmt.Sprintf("<p style=\"%s\">", rowStyle))
-		_, _ = w.WriteString(fmt.Sprintf("<strong style=\"%s\">%s: </strong>", styleDataLabel, util.EscapeHTML([]byte(headerLabel))))
-		w.Write(util.EscapeHTML([]byte(cellText)))
+// renderFootnoteList 在文末渲染 "注释" 区块
+func (r *wechatHTMLRenderer) renderFootnoteList(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	if entering {
+		_, _ = w.WriteString(fmt.Sprintf("<section style=\"%s\">\n", styleFootnoteSection))
+		_, _ = w.WriteString(fmt.Sprintf("<p style=\"%s\">注释</p>\n", styleFootnoteTitle))
+	} else {
+		_, _ = w.WriteString("</section>\n")
+	}
+	return ast.WalkContinue, nil
+}
+
+func (r *wechatHTMLRenderer) renderFootnote(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	n := node.(*ext_ast.Footnote)
+	if entering {
+		_, _ = w.WriteString(fmt.Sprintf("<p style=\"%s\">[%d] ", styleFootnoteItem, n.Index))
+	} else {
 		_, _ = w.WriteString("</p>\n")
 	}
+	return ast.WalkContinue, nil
+}
 
-	return ast.WalkSkipChildren, nil
+// renderStyledSpan 输出带颜色、高亮、字号、下划线或上下标的文字
+func (r *wechatHTMLRenderer) renderStyledSpan(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	n := node.(*styledSpan)
+	tag := n.Tag
+	if tag == "" {
+		tag = "span"
+	}
+	if entering {
+		_, _ = w.WriteString(fmt.Sprintf("<%s style=\"%s\">", tag, n.Style))
+	} else {
+		_, _ = w.WriteString(fmt.Sprintf("</%s>", tag))
+	}
+	return ast.WalkContinue, nil
 }
 
+// newMarkdown 创建渲染器，GFM 与脚注扩展提供表格、删除线与脚注节点的渲染
 func newMarkdown() goldmark.Markdown {
 	customRenderer := &wechatHTMLRenderer{}
 	return goldmark.New(
-		goldmark.WithExtensions(extension.GFM),
+		goldmark.WithExtensions(extension.GFM, extension.Footnote),
 		goldmark.WithRendererOptions(
 			// 注册 customRenderer，设置优先级为 200（数值越大，优先级越高）
 			renderer.WithNodeRenderers(
@@ -525,78 +659,163 @@ func newMarkdown() goldmark.Markdown {
 	)
 }
 
+// writeArticle 处理文章中的图片，打印 Markdown 并把微信 HTML 写入 outputFile，pipeline 为 nil 时不处理图片
+func writeArticle(ctx context.Context, article *Document, cfg *Config, pipeline *imagePipeline, outputFile string) error {
+	if pipeline != nil {
+		fmt.Printf("正在下载并处理图片到 %s ...\n", pipeline.dir)
+		if err := pipeline.process(ctx, article); err != nil {
+			return fmt.Errorf("处理图片失败: %v", err)
+		}
+	}
+
+	// print markdown content
+	fmt.Println(renderMarkdown(article))
+
+	body, err := renderArticle(article, cfg)
+	if err != nil {
+		return fmt.Errorf("渲染 HTML 失败: %v", err)
+	}
+
+	var htmlBuffer bytes.Buffer
+	htmlBuffer.WriteString(fmt.Sprintf("<div style=\"%s\">\n", styleBody))
+	htmlBuffer.Write(body)
+	htmlBuffer.WriteString("</div>")
+
+	if err := os.WriteFile(outputFile, htmlBuffer.Bytes(), 0644); err != nil {
+		return fmt.Errorf("写入 HTML 文件失败: %v", err)
+	}
+	return nil
+}
+
 func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "cache":
+			if err := runCacheCommand(os.Args[2:]); err != nil {
+				log.Fatalf("cache 命令失败: %v", err)
+			}
+			return
+		case "cover":
+			if err := runCoverCommand(os.Args[2:]); err != nil {
+				log.Fatalf("cover 命令失败: %v", err)
+			}
+			return
+		}
+	}
+
 	proxyAddr := flag.String("proxy", "", "SOCKS5 代理地址和端口, 例如: 127.0.0.1:1080")
+	typography := flag.Bool("typography", false, "启用中英文排版规范化 (中英文间加空格、全角标点、引号与省略号)")
+	configPath := flag.String("config", "config.json", "配置文件路径 (不存在时使用默认配置)")
+	imagesDir := flag.String("images-dir", "images", "图片下载目录 (按文档中的裁剪与旋转处理)，为空时不下载图片")
+	tab := flag.String("tab", "", "要转换的标签页 (标题或 ID)，默认为第一个标签页")
+	allTabs := flag.Bool("all-tabs", false, "把每个标签页分别转换为一篇文章 (output-<标签页标题>.html)")
+	suggestions := flag.String("suggestions", "", "未处理的建议修改: accept (全部接受)、reject (全部拒绝，默认) 或 fail (有建议时拒绝转换)")
+	target := flag.String("target", "", "输出目标: wechat (默认) 或 web，决定命名范围 wechat-only 与 web-only 中的内容是否保留")
 	flag.Parse()
 
 	if len(flag.Args()) < 1 {
-		log.Fatalf("用法: go run . [--proxy <addr:port>] <documentId>\n例如: go run . --proxy 127.0.0.1:1080 YOUR_DOC_ID_HERE")
+		log.Fatalf("用法: go run . [--proxy <addr:port>] [--config <file>] [--images-dir <dir>] [--tab <title|id> | --all-tabs] [--suggestions accept|reject|fail] [--target wechat|web] [--typography] <documentId>\n例如: go run . --proxy 127.0.0.1:1080 YOUR_DOC_ID_HERE")
 	}
 	docId := flag.Args()[0]
 
-	b, err := os.ReadFile("credentials.json")
+	cfg, err := loadConfig(*configPath)
 	if err != nil {
-		log.Fatalf("无法读取客户端密钥文件 (credentials.json): %v", err)
+		log.Fatalf("加载配置失败: %v", err)
 	}
-
-	config, err := google.ConfigFromJSON(b, docs.DocumentsReadonlyScope)
+	if *typography {
+		cfg.Typography = true
+	}
+	if *suggestions != "" {
+		if _, ok := suggestionsViewModes[*suggestions]; !ok {
+			log.Fatalf("--suggestions 只能是 accept、reject 或 fail: %q", *suggestions)
+		}
+		cfg.Suggestions = *suggestions
+	}
+	if *target != "" {
+		if !outputTargets[*target] {
+			log.Fatalf("--target 只能是 wechat 或 web: %q", *target)
+		}
+		cfg.Target = *target
+	}
+	uploader, err := newUploader(cfg.Upload, cfg.WeChat)
 	if err != nil {
-		log.Fatalf("无法解析客户端密钥文件为配置: %v", err)
+		log.Fatalf("创建图床失败: %v", err)
 	}
 
 	ctx := context.Background()
-	if *proxyAddr != "" {
-		fmt.Printf("使用 SOCKS5 代理: %s\n", *proxyAddr)
-		dialer, err := proxy.SOCKS5("tcp", *proxyAddr, nil, proxy.Direct)
-		if err != nil {
-			log.Fatalf("无法创建 SOCKS5 代理拨号器: %v", err)
-		}
-
-		httpTransport := &http.Transport{}
-		httpTransport.DialContext = dialer.(proxy.ContextDialer).DialContext
-
-		httpClient := &http.Client{Transport: httpTransport}
-		ctx = context.WithValue(ctx, oauth2.HTTPClient, httpClient)
+	srv, client, err := newDocsService(ctx, *proxyAddr)
+	if err != nil {
+		log.Fatalf("%v", err)
 	}
 
-	client := getClient(ctx, config)
-	srv, err := docs.NewService(ctx, option.WithHTTPClient(client))
-	if err != nil {
-		log.Fatalf("无法创建 Docs 服务: %v", err)
+	if *tab != "" && *allTabs {
+		log.Fatalf("--tab 与 --all-tabs 不能同时使用")
 	}
 
 	fmt.Println("正在从 Google Docs 获取并解析文档...")
-	markdownContent, err := processDocument(srv, docId)
+	articles, err := processDocument(srv, docId, cfg, *tab, *allTabs)
 	if err != nil {
 		log.Fatalf("处理文档失败: %v", err)
 	}
 
-	// print markdown content
-	fmt.Println(markdownContent)
-
-	md := newMarkdown()
-
-	var htmlBuffer bytes.Buffer
-	htmlBuffer.WriteString(fmt.Sprintf("<div style=\"%s\">\n", styleBody))
-	if err := md.Convert([]byte(markdownContent), &htmlBuffer); err != nil {
-		log.Fatalf("Markdown 转换为 HTML 失败: %v", err)
+	var pipeline *imagePipeline
+	if *imagesDir != "" {
+		if hasDrawings(articles) {
+			if client, err = newDriveClient(ctx, *proxyAddr); err != nil {
+				log.Fatalf("%v", err)
+			}
+		}
+		var cache *imageCache
+		if uploader != nil && cfg.Upload.Cache != "" {
+			if cache, err = loadImageCache(cfg.Upload.Cache); err != nil {
+				log.Fatalf("加载图片缓存失败: %v", err)
+			}
+		}
+		if pipeline, err = newImagePipeline(client, *imagesDir, cfg.Images, uploader, cfg.Upload.Prefix, cache); err != nil {
+			log.Fatalf("初始化图片处理失败: %v", err)
+		}
 	}
-	htmlBuffer.WriteString("</div>")
 
-	outputFile := "output.html"
-	err = os.WriteFile(outputFile, htmlBuffer.Bytes(), 0644)
-	if err != nil {
-		log.Fatalf("写入 HTML 文件失败: %v", err)
+	var outputFiles []string
+	seen := map[string]bool{}
+	tabFiles := map[string]string{}
+	for _, article := range articles {
+		outputFile := "output.html"
+		if *allTabs {
+			if tabFiles[article.TabID] == "" {
+				outputFile = tabOutputFile(&docTab{ID: article.TabID, Title: article.TabTitle})
+				// 标题相同的标签页用 ID 区分
+				if seen[outputFile] {
+					outputFile = tabOutputFile(&docTab{ID: article.TabID, Title: article.TabTitle + "-" + article.TabID})
+				}
+				seen[outputFile] = true
+				tabFiles[article.TabID] = outputFile
+			}
+			outputFile = tabFiles[article.TabID]
+		}
+		// 分割后的每一篇文章写入 output-1.html、output-2.html ...
+		if article.Part > 0 {
+			outputFile = fmt.Sprintf("%s-%d.html", strings.TrimSuffix(outputFile, ".html"), article.Part)
+		}
+		if err := writeArticle(ctx, article, cfg, pipeline, outputFile); err != nil {
+			log.Fatalf("%v", err)
+		}
+		outputFiles = append(outputFiles, outputFile)
 	}
+	outputFile := strings.Join(outputFiles, ", ")
 
 	fmt.Println("\n=======================================================")
 	fmt.Printf("🎉 转换成功！结果已保存到 %s\n", outputFile)
 	fmt.Println("\n下一步操作:")
-	fmt.Println("1. 打开 output.html 文件，你会看到渲染后的效果。")
-	fmt.Println("2. 【重要】检查文件中的图片 URL，它们是占位符。你需要：")
-	fmt.Println("   a. 将 Google Doc 中的图片手动保存下来。")
-	fmt.Println("   b. 上传到你自己的服务器、CDN 或图床（如腾讯云 COS）。")
-	fmt.Println("   c. 将 `output.html` 中 `https://your-cdn.com/...` 这样的占位符 URL 替换为真实的图片 URL。")
+	fmt.Printf("1. 打开 %s 文件，你会看到渲染后的效果。\n", outputFile)
+	if uploader != nil && *imagesDir != "" {
+		fmt.Printf("2. 图片已上传到 %s 图床，请检查上面的日志中是否有上传失败的图片 (仍为占位符)。\n", uploader.Name())
+	} else {
+		fmt.Println("2. 【重要】检查文件中的图片 URL，它们是占位符。你需要：")
+		fmt.Println("   a. 图片已按文档中的裁剪与旋转处理后保存在 images 目录 (--images-dir)。")
+		fmt.Println("   b. 上传到你自己的服务器、CDN 或图床，或在配置文件的 upload 中配置图床自动上传。")
+		fmt.Println("   c. 将 `output.html` 中 `https://your-cdn.com/...` 这样的占位符 URL 替换为真实的图片 URL。")
+	}
 	fmt.Println("3. 用浏览器打开修改后的 `output.html` 文件，全选 (Ctrl+A / Cmd+A) 并复制 (Ctrl+C / Cmd+C)。")
 	fmt.Println("4. 粘贴到微信公众号后台的编辑器中。")
 	fmt.Println("=======================================================")
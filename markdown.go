@@ -0,0 +1,334 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// renderMarkdown 把文档模型序列化为 Markdown
+func renderMarkdown(d *Document) string {
+	var sb strings.Builder
+	for _, block := range d.Blocks {
+		writeMarkdownBlock(&sb, block, 0)
+	}
+	if len(d.Footnotes) > 0 {
+		for _, fn := range d.Footnotes {
+			var parts []string
+			for _, block := range fn.Blocks {
+				if p, ok := block.(*Paragraph); ok {
+					parts = append(parts, renderInlinesMarkdown(p.Inlines, mdText))
+				}
+			}
+			fmt.Fprintf(&sb, "[^%d]: %s\n", fn.Number, strings.Join(parts, " "))
+		}
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
+
+func writeMarkdownBlock(sb *strings.Builder, block Block, indent int) {
+	switch v := block.(type) {
+	case *Heading:
+		sb.WriteString(strings.Repeat("#", v.Level) + " ")
+		sb.WriteString(renderInlinesMarkdown(v.Inlines, mdText))
+		sb.WriteString("\n\n")
+	case *Paragraph:
+		sb.WriteString(escapeLines(renderInlinesMarkdown(v.Inlines, mdText)))
+		sb.WriteString("\n\n")
+	case *Blockquote:
+		var inner strings.Builder
+		for _, child := range v.Blocks {
+			writeMarkdownBlock(&inner, child, 0)
+		}
+		lines := strings.Split(strings.TrimRight(inner.String(), "\n"), "\n")
+		for _, line := range lines {
+			sb.WriteString(strings.TrimRight("> "+line, " ") + "\n")
+		}
+		sb.WriteString("\n")
+	case *Callout:
+		// GitHub 提示的写法，与默认标题不同时在第一行写出标题
+		inner := fmt.Sprintf("[!%s]\n", strings.ToUpper(v.Kind))
+		if v.Title != calloutKinds[v.Kind].Title {
+			inner += fmt.Sprintf("**%s**\n", escapeMarkdown(v.Title, mdText))
+		}
+		var body strings.Builder
+		for _, child := range v.Blocks {
+			writeMarkdownBlock(&body, child, 0)
+		}
+		for _, line := range strings.Split(strings.TrimRight(inner+body.String(), "\n"), "\n") {
+			sb.WriteString(strings.TrimRight("> "+line, " ") + "\n")
+		}
+		sb.WriteString("\n")
+	case *Divider:
+		sb.WriteString("---\n\n")
+	case *Figure:
+		sb.WriteString(renderInlinesMarkdown([]Inline{v.Image}, mdText))
+		sb.WriteString("\n\n")
+		if v.Label != "" || len(v.Caption) > 0 {
+			caption := renderInlinesMarkdown(v.Caption, mdText)
+			if v.Label != "" {
+				caption = strings.TrimSpace(fmt.Sprintf("**%s** %s", escapeMarkdown(v.Label, mdText), caption))
+			}
+			sb.WriteString(escapeLines(caption))
+			sb.WriteString("\n\n")
+		}
+	case *List:
+		writeMarkdownList(sb, v, indent)
+		if indent == 0 {
+			sb.WriteString("\n")
+		}
+	case *Table:
+		writeMarkdownTable(sb, v)
+	}
+}
+
+func writeMarkdownList(sb *strings.Builder, list *List, indent int) {
+	for i, item := range list.Items {
+		marker := "* "
+		if list.Ordered {
+			marker = fmt.Sprintf("%d. ", i+1)
+		}
+		sb.WriteString(strings.Repeat(" ", indent) + marker)
+		switch {
+		case list.Checklist && item.Checked:
+			sb.WriteString("[x] ")
+		case list.Checklist:
+			sb.WriteString("[ ] ")
+		}
+		for j, block := range item.Blocks {
+			switch v := block.(type) {
+			case *Paragraph:
+				if j > 0 {
+					sb.WriteString(strings.Repeat(" ", indent+len(marker)))
+				}
+				sb.WriteString(escapeLines(renderInlinesMarkdown(v.Inlines, mdText)))
+				sb.WriteString("\n")
+			case *List:
+				writeMarkdownList(sb, v, indent+len(marker))
+			}
+		}
+		if len(item.Blocks) == 0 {
+			sb.WriteString("\n")
+		}
+	}
+}
+
+func writeMarkdownTable(sb *strings.Builder, table *Table) {
+	for i, row := range table.Rows {
+		var rowContent []string
+		for _, cell := range row.Cells {
+			rowContent = append(rowContent, renderInlinesMarkdown(cell.Inlines, mdTableCell))
+		}
+		// 构建 Markdown 表格行
+		sb.WriteString("| " + strings.Join(rowContent, " | ") + " |\n")
+
+		// 如果是第一行（表头），则在下面添加分隔线
+		if i == 0 {
+			var headerSeparators []string
+			for range row.Cells {
+				headerSeparators = append(headerSeparators, "---")
+			}
+			sb.WriteString("| " + strings.Join(headerSeparators, " | ") + " |\n")
+		}
+	}
+	sb.WriteString("\n") // 表格结束后添加一个换行符
+}
+
+// escapeLines 对手动换行分隔的每一行分别做行首转义
+func escapeLines(s string) string {
+	lines := strings.Split(s, "\\\n")
+	for i, line := range lines {
+		lines[i] = escapeLineStart(line)
+	}
+	return strings.Join(lines, "\\\n")
+}
+
+// renderInlinesMarkdown 渲染行内元素，连续的文本交给 renderTexts 统一处理强调标记
+func renderInlinesMarkdown(inlines []Inline, ctx mdContext) string {
+	var sb strings.Builder
+	var texts []*Text
+	flush := func() {
+		sb.WriteString(renderTexts(texts, ctx))
+		texts = nil
+	}
+	for _, in := range inlines {
+		switch v := in.(type) {
+		case *Text:
+			texts = append(texts, v)
+			continue
+		}
+		flush()
+		switch v := in.(type) {
+		case *Image:
+			switch {
+			case v.Formula != nil && v.Formula.Display:
+				fmt.Fprintf(&sb, "$$%s$$", v.Formula.TeX)
+			case v.Formula != nil:
+				fmt.Fprintf(&sb, "$%s$", v.Formula.TeX)
+			default:
+				fmt.Fprintf(&sb, "![%s](%s)", escapeMarkdown(v.Alt, mdLinkText), escapeLinkDestination(v.URL))
+			}
+		case *LineBreak:
+			if ctx == mdTableCell {
+				sb.WriteString(" ")
+			} else {
+				sb.WriteString("\\\n")
+			}
+		case *FootnoteRef:
+			fmt.Fprintf(&sb, "[^%d]", v.Number)
+		}
+	}
+	flush()
+	return sb.String()
+}
+
+// renderTexts 把一组文本转换为 Markdown，链接相同的文本共用一个链接，
+// 强调标记按需开启和关闭，首尾空白移到标记之外 (CommonMark 不识别 "**bold **")
+func renderTexts(texts []*Text, ctx mdContext) string {
+	var sb strings.Builder
+	for start := 0; start < len(texts); {
+		end := start + 1
+		for end < len(texts) && texts[end].Style.Link == texts[start].Style.Link {
+			end++
+		}
+		group := texts[start:end]
+		if link := group[0].Style.Link; link != "" {
+			lead, inner, trail := splitOuterSpace(renderEmphasis(group, mdLinkText))
+			sb.WriteString(lead)
+			if inner != "" {
+				fmt.Fprintf(&sb, "[%s](%s)", inner, escapeLinkDestination(link))
+			}
+			sb.WriteString(trail)
+		} else {
+			sb.WriteString(renderEmphasis(group, ctx))
+		}
+		start = end
+	}
+	return sb.String()
+}
+
+// markdownMarkers 返回样式需要的强调标记，按从外到内的顺序排列
+func markdownMarkers(style TextStyle) []string {
+	var m []string
+	if style.Strikethrough {
+		m = append(m, "~~")
+	}
+	if style.Bold {
+		m = append(m, "**")
+	}
+	if style.Italic {
+		m = append(m, "*")
+	}
+	return m
+}
+
+// emphasisSeparator 隔开相邻的强调标记。CommonMark 根据标记前后的字符判断它能否开启或关闭强调，
+// 在词内 (如中文之间) 切换样式时，连在一起的标记 (如 "**a*b****c*") 会被错误配对。
+// 空的 HTML 注释在页面上不可见，却能让每个标记各自成为一个分隔符串
+const emphasisSeparator = "<!---->"
+
+// renderEmphasis 渲染一组链接相同的文本，维护一个已开启标记的栈
+func renderEmphasis(texts []*Text, ctx mdContext) string {
+	var b strings.Builder
+	var open []string
+	pending := "" // 等待写出的空白，需放在关闭标记之后
+
+	for _, t := range texts {
+		lead, core, trail := splitOuterSpace(t.Text)
+		if core == "" {
+			// 纯空白文本的样式没有意义，直接并入待写空白
+			pending += t.Text
+			continue
+		}
+
+		want := markdownMarkers(t.Style)
+		// 保留栈底仍然需要的标记，其余的从栈顶开始关闭
+		keep := 0
+		for keep < len(open) && containsMarker(want, open[keep]) {
+			keep++
+		}
+		var closers, openers []string
+		for j := len(open) - 1; j >= keep; j-- {
+			closers = append(closers, open[j])
+		}
+		open = open[:keep]
+		for _, m := range want {
+			if !containsMarker(open, m) {
+				openers = append(openers, m)
+				open = append(open, m)
+			}
+		}
+
+		if pending == "" && lead == "" && b.Len() > 0 && len(closers)+len(openers) > 0 {
+			// 词内切换样式：标记之间、开启标记之前与关闭标记之后都加上分隔符，
+			// 使关闭标记紧跟文字，开启标记紧贴文字
+			if len(closers) == 0 {
+				b.WriteString(emphasisSeparator)
+			}
+			b.WriteString(strings.Join(append(closers, openers...), emphasisSeparator))
+			if len(openers) == 0 {
+				b.WriteString(emphasisSeparator)
+			}
+		} else {
+			b.WriteString(joinMarkers(closers))
+			b.WriteString(pending)
+			b.WriteString(lead)
+			b.WriteString(joinMarkers(openers))
+		}
+		b.WriteString(wrapHTMLStyle(escapeMarkdown(core, ctx), t.Style))
+		pending = trail
+	}
+	var closers []string
+	for j := len(open) - 1; j >= 0; j-- {
+		closers = append(closers, open[j])
+	}
+	b.WriteString(joinMarkers(closers))
+	b.WriteString(pending)
+	return b.String()
+}
+
+// joinMarkers 连接同一处开启或关闭的标记。"**" 与 "*" 相邻时会合并为 "***"，
+// 之后只关闭其中一层时容易拆错，所以用分隔符隔开
+func joinMarkers(markers []string) string {
+	var sb strings.Builder
+	for i, m := range markers {
+		if i > 0 && markers[i-1][0] == m[0] {
+			sb.WriteString(emphasisSeparator)
+		}
+		sb.WriteString(m)
+	}
+	return sb.String()
+}
+
+// wrapHTMLStyle 用内联 HTML 表示 Markdown 没有的样式 (下划线、上下标)
+func wrapHTMLStyle(s string, style TextStyle) string {
+	switch style.Baseline {
+	case "SUPERSCRIPT":
+		s = "<sup>" + s + "</sup>"
+	case "SUBSCRIPT":
+		s = "<sub>" + s + "</sub>"
+	}
+	if style.Underline {
+		s = "<u>" + s + "</u>"
+	}
+	return s
+}
+
+func containsMarker(markers []string, m string) bool {
+	for _, x := range markers {
+		if x == m {
+			return true
+		}
+	}
+	return false
+}
+
+// splitOuterSpace 把文本拆分为前导空白、正文和尾随空白
+func splitOuterSpace(s string) (lead, core, trail string) {
+	core = strings.TrimLeftFunc(s, unicode.IsSpace)
+	lead = s[:len(s)-len(core)]
+	trimmed := strings.TrimRightFunc(core, unicode.IsSpace)
+	trail = core[len(trimmed):]
+	return lead, trimmed, trail
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
+)
+
+func TestRenderTextsEmphasis(t *testing.T) {
+	bold, italic, both := TextStyle{Bold: true}, TextStyle{Italic: true}, TextStyle{Bold: true, Italic: true}
+	tests := []struct {
+		name  string
+		texts []*Text
+		want  string
+	}{
+		{"相邻的粗体合并", []*Text{{Text: "foo", Style: bold}, {Text: "bar", Style: bold}},
+			"<strong>foobar</strong>"},
+		{"空白移到标记之外", []*Text{{Text: "粗体 ", Style: bold}, {Text: "正文"}},
+			"<strong>粗体</strong> 正文"},
+		{"粗体 → 粗斜体 → 斜体", []*Text{{Text: "粗", Style: bold}, {Text: "粗斜", Style: both}, {Text: "斜", Style: italic}},
+			"<strong>粗<em>粗斜</em></strong><em>斜</em>"},
+		{"斜体 → 粗斜体 → 粗体", []*Text{{Text: "a", Style: italic}, {Text: "b", Style: both}, {Text: "c", Style: bold}},
+			"<em>a<strong>b</strong></em><strong>c</strong>"},
+		{"词内切换", []*Text{{Text: "正文"}, {Text: "强调", Style: bold}, {Text: "正文"}},
+			"正文<strong>强调</strong>正文"},
+		{"删除线中的粗体", []*Text{{Text: "旧", Style: TextStyle{Strikethrough: true}}, {Text: "价", Style: TextStyle{Strikethrough: true, Bold: true}}},
+			"<del>旧<strong>价</strong></del>"},
+		{"链接中的强调", []*Text{{Text: "文档", Style: TextStyle{Link: "https://example.com"}}, {Text: "链接", Style: TextStyle{Link: "https://example.com", Bold: true}}},
+			`<a href="https://example.com">文档<strong>链接</strong></a>`},
+	}
+	md := goldmark.New(goldmark.WithExtensions(extension.Strikethrough))
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src := renderTexts(tt.texts, mdText)
+			var buf bytes.Buffer
+			if err := md.Convert([]byte(src), &buf); err != nil {
+				t.Fatal(err)
+			}
+			got := strings.TrimSuffix(strings.TrimPrefix(strings.TrimSpace(buf.String()), "<p>"), "</p>")
+			got = strings.ReplaceAll(got, "<!-- raw HTML omitted -->", "")
+			if got != tt.want {
+				t.Errorf("Markdown %q\n渲染为 %s\n期望   %s", src, got, tt.want)
+			}
+		})
+	}
+}
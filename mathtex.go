@@ -0,0 +1,1197 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"math"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/go-fonts/stix/stix2mathregular"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/font/sfnt"
+	"golang.org/x/image/math/fixed"
+	"golang.org/x/image/vector"
+)
+
+// 离线渲染 LaTeX 数学公式：解析常用的 LaTeX 数学子集，按简化的 TeX 规则排版，
+// 用 STIX Two Math 字体绘制为透明背景的 PNG。微信中不能运行 MathJax，公式只能以图片发布
+
+// 公式按 3 倍正文字号 (16px) 渲染，在高分屏上也清晰；独立成段的公式放大显示
+const (
+	formulaPixelsPerEm  = 48
+	formulaDisplayScale = 1.2
+)
+
+// 各级上下标相对于正文的字号
+var mathScriptScales = [3]float64{1, 0.7, 0.5}
+
+// atomKind 是 TeX 中决定间距的原子类型
+type atomKind int
+
+const (
+	atomOrd atomKind = iota
+	atomOp
+	atomBin
+	atomRel
+	atomOpen
+	atomClose
+	atomPunct
+	atomInner
+)
+
+// mathBox 是排版后的盒子，坐标以像素为单位，y 向下增大
+type mathBox struct {
+	w, h, d float64 // 宽度、基线以上的高度、基线以下的深度
+	kind    atomKind
+	// limits 为 true 时，独立公式中的上下标放在运算符的正上方与正下方 (\sum、\lim 等)
+	limits bool
+	draw   func(dst draw.Image, x, y float64) // y 是基线位置
+}
+
+// mathFont 是字母使用的数学字体
+type mathFont int
+
+const (
+	mathItalic mathFont = iota
+	mathRoman
+	mathBold
+	mathBlackboard
+	mathCalligraphic
+)
+
+// mathStyle 是排版状态
+type mathStyle struct {
+	base    float64 // 正文字号 (像素)
+	level   int     // 0 正文，1 上下标，2 二级上下标
+	display bool
+	font    mathFont
+}
+
+func (st mathStyle) size() float64 {
+	return st.base * mathScriptScales[st.level]
+}
+
+// script 返回上下标使用的样式
+func (st mathStyle) script() mathStyle {
+	st.level = min(st.level+1, 2)
+	st.display = false
+	return st
+}
+
+// fraction 返回分子分母使用的样式：独立公式中与正文同样大小，否则缩小一级
+func (st mathStyle) fraction() mathStyle {
+	if st.display {
+		st.display = false
+		return st
+	}
+	return st.script()
+}
+
+// 数学轴 (分数线、加号中心) 在基线以上的高度 (em)
+const mathAxis = 0.25
+
+// mathSymbols 是符号命令与其字符、原子类型
+var mathSymbols = map[string]struct {
+	text string
+	kind atomKind
+}{
+	// 二元运算符
+	`\pm`: {"±", atomBin}, `\mp`: {"∓", atomBin}, `\times`: {"×", atomBin}, `\div`: {"÷", atomBin},
+	`\cdot`: {"⋅", atomBin}, `\ast`: {"∗", atomBin}, `\star`: {"⋆", atomBin}, `\circ`: {"∘", atomBin},
+	`\bullet`: {"∙", atomBin}, `\cup`: {"∪", atomBin}, `\cap`: {"∩", atomBin}, `\setminus`: {"∖", atomBin},
+	`\wedge`: {"∧", atomBin}, `\land`: {"∧", atomBin}, `\vee`: {"∨", atomBin}, `\lor`: {"∨", atomBin},
+	`\oplus`: {"⊕", atomBin}, `\otimes`: {"⊗", atomBin},
+	// 关系符
+	`\leq`: {"≤", atomRel}, `\le`: {"≤", atomRel}, `\geq`: {"≥", atomRel}, `\ge`: {"≥", atomRel},
+	`\neq`: {"≠", atomRel}, `\ne`: {"≠", atomRel}, `\approx`: {"≈", atomRel}, `\equiv`: {"≡", atomRel},
+	`\sim`: {"∼", atomRel}, `\simeq`: {"≃", atomRel}, `\cong`: {"≅", atomRel}, `\propto`: {"∝", atomRel},
+	`\ll`: {"≪", atomRel}, `\gg`: {"≫", atomRel}, `\in`: {"∈", atomRel}, `\notin`: {"∉", atomRel},
+	`\ni`: {"∋", atomRel}, `\subset`: {"⊂", atomRel}, `\supset`: {"⊃", atomRel}, `\subseteq`: {"⊆", atomRel},
+	`\supseteq`: {"⊇", atomRel}, `\perp`: {"⊥", atomRel}, `\parallel`: {"∥", atomRel}, `\mid`: {"∣", atomRel},
+	`\to`: {"→", atomRel}, `\rightarrow`: {"→", atomRel}, `\leftarrow`: {"←", atomRel}, `\gets`: {"←", atomRel},
+	`\Rightarrow`: {"⇒", atomRel}, `\Leftarrow`: {"⇐", atomRel}, `\Leftrightarrow`: {"⇔", atomRel},
+	`\leftrightarrow`: {"↔", atomRel}, `\implies`: {"⟹", atomRel}, `\iff`: {"⟺", atomRel},
+	`\mapsto`: {"↦", atomRel}, `\longrightarrow`: {"⟶", atomRel}, `\uparrow`: {"↑", atomRel}, `\downarrow`: {"↓", atomRel},
+	`\coloneqq`: {"≔", atomRel},
+	// 普通符号
+	`\infty`: {"∞", atomOrd}, `\partial`: {"∂", atomOrd}, `\nabla`: {"∇", atomOrd}, `\forall`: {"∀", atomOrd},
+	`\exists`: {"∃", atomOrd}, `\nexists`: {"∄", atomOrd}, `\emptyset`: {"∅", atomOrd}, `\varnothing`: {"∅", atomOrd},
+	`\neg`: {"¬", atomOrd}, `\lnot`: {"¬", atomOrd}, `\angle`: {"∠", atomOrd}, `\triangle`: {"△", atomOrd},
+	`\hbar`: {"ℏ", atomOrd}, `\ell`: {"ℓ", atomOrd}, `\Re`: {"ℜ", atomOrd}, `\Im`: {"ℑ", atomOrd},
+	`\aleph`: {"ℵ", atomOrd}, `\prime`: {"′", atomOrd}, `\degree`: {"°", atomOrd}, `\dagger`: {"†", atomOrd},
+	`\ldots`: {"…", atomInner}, `\dots`: {"…", atomInner}, `\cdots`: {"⋯", atomInner}, `\vdots`: {"⋮", atomOrd},
+	`\ddots`: {"⋱", atomInner}, `\therefore`: {"∴", atomRel}, `\because`: {"∵", atomRel},
+	`\{`: {"{", atomOpen}, `\}`: {"}", atomClose}, `\|`: {"‖", atomOrd}, `\%`: {"%", atomOrd},
+	`\$`: {"$", atomOrd}, `\#`: {"#", atomOrd}, `\&`: {"&", atomOrd}, `\_`: {"_", atomOrd},
+	`\langle`: {"⟨", atomOpen}, `\rangle`: {"⟩", atomClose}, `\lfloor`: {"⌊", atomOpen}, `\rfloor`: {"⌋", atomClose},
+	`\lceil`: {"⌈", atomOpen}, `\rceil`: {"⌉", atomClose}, `\vert`: {"|", atomOrd}, `\Vert`: {"‖", atomOrd},
+	`\lbrace`: {"{", atomOpen}, `\rbrace`: {"}", atomClose}, `\lbrack`: {"[", atomOpen}, `\rbrack`: {"]", atomClose},
+}
+
+// 小写希腊字母按 TeX 的习惯使用斜体，大写希腊字母使用正体
+var mathGreek = map[string]rune{
+	`\alpha`: 'α', `\beta`: 'β', `\gamma`: 'γ', `\delta`: 'δ', `\varepsilon`: 'ε', `\zeta`: 'ζ',
+	`\eta`: 'η', `\theta`: 'θ', `\iota`: 'ι', `\kappa`: 'κ', `\lambda`: 'λ', `\mu`: 'μ', `\nu`: 'ν',
+	`\xi`: 'ξ', `\omicron`: 'ο', `\pi`: 'π', `\rho`: 'ρ', `\varsigma`: 'ς', `\sigma`: 'σ', `\tau`: 'τ',
+	`\upsilon`: 'υ', `\varphi`: 'φ', `\chi`: 'χ', `\psi`: 'ψ', `\omega`: 'ω',
+	`\epsilon`: 'ϵ', `\vartheta`: 'ϑ', `\phi`: 'ϕ', `\varrho`: 'ϱ', `\varpi`: 'ϖ',
+	`\Gamma`: 'Γ', `\Delta`: 'Δ', `\Theta`: 'Θ', `\Lambda`: 'Λ', `\Xi`: 'Ξ', `\Pi`: 'Π', `\Sigma`: 'Σ',
+	`\Upsilon`: 'Υ', `\Phi`: 'Φ', `\Psi`: 'Ψ', `\Omega`: 'Ω',
+}
+
+// 希腊字母符号变体在数学斜体区段中的位置
+var mathItalicGreekVariants = map[rune]rune{
+	'ϵ': 0x1D716, 'ϑ': 0x1D717, 'ϕ': 0x1D719, 'ϱ': 0x1D71A, 'ϖ': 0x1D71B,
+}
+
+// mathBigOps 是大型运算符，limits 为 true 时独立公式中的上下标放在正上方与正下方
+var mathBigOps = map[string]struct {
+	text   string
+	limits bool
+}{
+	`\sum`: {"∑", true}, `\prod`: {"∏", true}, `\coprod`: {"∐", true},
+	`\bigcup`: {"⋃", true}, `\bigcap`: {"⋂", true}, `\bigoplus`: {"⨁", true}, `\bigotimes`: {"⨂", true},
+	`\int`: {"∫", false}, `\iint`: {"∬", false}, `\iiint`: {"∭", false}, `\oint`: {"∮", false},
+}
+
+// mathFunctions 是以正体显示的函数名，值为 true 时独立公式中的下标放在正下方 (\lim、\max 等)
+var mathFunctions = map[string]bool{
+	`\sin`: false, `\cos`: false, `\tan`: false, `\cot`: false, `\sec`: false, `\csc`: false,
+	`\arcsin`: false, `\arccos`: false, `\arctan`: false, `\sinh`: false, `\cosh`: false, `\tanh`: false,
+	`\log`: false, `\ln`: false, `\lg`: false, `\exp`: false, `\deg`: false, `\dim`: false,
+	`\ker`: false, `\arg`: false, `\hom`: false,
+	`\lim`: true, `\max`: true, `\min`: true, `\sup`: true, `\inf`: true, `\det`: true,
+	`\gcd`: true, `\Pr`: true, `\argmax`: true, `\argmin`: true, `\limsup`: true, `\liminf`: true,
+}
+
+// 重音命令与其字符，横线类重音直接画线
+var mathAccents = map[string]string{
+	`\hat`: "ˆ", `\widehat`: "ˆ", `\tilde`: "˜", `\widetilde`: "˜", `\dot`: "˙", `\ddot`: "¨",
+	`\vec`: "→", `\check`: "ˇ", `\breve`: "˘", `\acute`: "´", `\grave`: "`",
+}
+
+// 间距命令 (em)
+var mathSpaces = map[string]float64{
+	`\,`: 3.0 / 18, `\:`: 4.0 / 18, `\>`: 4.0 / 18, `\;`: 5.0 / 18, `\!`: -3.0 / 18,
+	`\ `: 1.0 / 3, `~`: 1.0 / 3, `\quad`: 1, `\qquad`: 2,
+}
+
+// 矩阵类环境两侧的定界符
+var mathEnvDelims = map[string][2]string{
+	"matrix": {".", "."}, "pmatrix": {"(", ")"}, "bmatrix": {"[", "]"}, "Bmatrix": {"{", "}"},
+	"vmatrix": {"|", "|"}, "Vmatrix": {"‖", "‖"}, "cases": {"{", "."}, "array": {".", "."},
+	"aligned": {".", "."}, "align": {".", "."}, "align*": {".", "."}, "gathered": {".", "."},
+	"split": {".", "."},
+}
+
+var loadMathFont = sync.OnceValues(func() (*sfnt.Font, error) {
+	return opentype.Parse(stix2mathregular.TTF)
+})
+
+// mathRenderer 保存字体与各字号的 face
+type mathRenderer struct {
+	font  *sfnt.Font
+	faces map[float64]font.Face
+	color image.Image
+}
+
+// renderFormula 把 LaTeX 公式渲染为 PNG，返回图片数据以及显示时的高度与深度 (em)
+func renderFormula(tex string, display bool) ([]byte, *Formula, error) {
+	f, err := loadMathFont()
+	if err != nil {
+		return nil, nil, fmt.Errorf("无法加载数学字体: %v", err)
+	}
+	r := &mathRenderer{font: f, faces: map[float64]font.Face{}, color: image.NewUniform(hexColor(colorText, color.Black))}
+	defer func() {
+		for _, face := range r.faces {
+			face.Close()
+		}
+	}()
+
+	scale := 1.0
+	if display {
+		scale = formulaDisplayScale
+	}
+	p := &mathParser{r: r, toks: tokenizeTeX(tex)}
+	box, err := p.parseFormula(mathStyle{base: formulaPixelsPerEm * scale, display: display})
+	if err != nil {
+		return nil, nil, err
+	}
+	if box.w <= 0 {
+		return nil, nil, fmt.Errorf("公式为空")
+	}
+
+	pad := math.Ceil(formulaPixelsPerEm * 0.08)
+	top, bottom, width := math.Ceil(box.h+pad), math.Ceil(box.d+pad), math.Ceil(box.w+2*pad)
+	img := image.NewRGBA(image.Rect(0, 0, int(width), int(top+bottom)))
+	box.draw(img, pad, top)
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, nil, err
+	}
+	return buf.Bytes(), &Formula{
+		TeX:     tex,
+		Display: display,
+		Width:   width / formulaPixelsPerEm,
+		Height:  top / formulaPixelsPerEm,
+		Depth:   bottom / formulaPixelsPerEm,
+	}, nil
+}
+
+func (r *mathRenderer) face(size float64) font.Face {
+	size = math.Round(size*4) / 4
+	if face, ok := r.faces[size]; ok {
+		return face
+	}
+	face, err := opentype.NewFace(r.font, &opentype.FaceOptions{Size: size, DPI: 72, Hinting: font.HintingNone})
+	if err != nil {
+		// 字体已经解析成功，只有字号非法时才会出错
+		panic(err)
+	}
+	r.faces[size] = face
+	return face
+}
+
+// glyph 排版一串字符，高度与深度取字形的实际轮廓
+func (r *mathRenderer) glyph(s string, size float64, kind atomKind) mathBox {
+	b := r.ink(s, size, kind)
+	b.h, b.d = max(0, b.h), max(0, b.d)
+	return b
+}
+
+// ink 与 glyph 相同，但保留轮廓的实际位置：完全位于基线以上的字形 (如重音) 深度为负
+func (r *mathRenderer) ink(s string, size float64, kind atomKind) mathBox {
+	face := r.face(size)
+	bounds, advance := font.BoundString(face, s)
+	return mathBox{
+		w:    fixedToFloat(advance),
+		h:    -fixedToFloat(bounds.Min.Y),
+		d:    fixedToFloat(bounds.Max.Y),
+		kind: kind,
+		draw: func(dst draw.Image, x, y float64) {
+			d := font.Drawer{Dst: dst, Src: r.color, Face: face, Dot: fixed.Point26_6{X: floatToFixed(x), Y: floatToFixed(y)}}
+			d.DrawString(s)
+		},
+	}
+}
+
+// fill 填充多边形
+func (r *mathRenderer) fill(dst draw.Image, points ...[2]float64) {
+	b := dst.Bounds()
+	ras := vector.NewRasterizer(b.Dx(), b.Dy())
+	ras.MoveTo(float32(points[0][0]), float32(points[0][1]))
+	for _, pt := range points[1:] {
+		ras.LineTo(float32(pt[0]), float32(pt[1]))
+	}
+	ras.ClosePath()
+	ras.Draw(dst, b, r.color, image.Point{})
+}
+
+// rule 画一条水平线，y 是线的中心
+func (r *mathRenderer) rule(dst draw.Image, x0, x1, y, thickness float64) {
+	r.fill(dst, [2]float64{x0, y - thickness/2}, [2]float64{x1, y - thickness/2},
+		[2]float64{x1, y + thickness/2}, [2]float64{x0, y + thickness/2})
+}
+
+// stroke 画一条有宽度的线段
+func (r *mathRenderer) stroke(dst draw.Image, x0, y0, x1, y1, width float64) {
+	dx, dy := x1-x0, y1-y0
+	l := math.Hypot(dx, dy)
+	if l == 0 {
+		return
+	}
+	nx, ny := -dy/l*width/2, dx/l*width/2
+	r.fill(dst, [2]float64{x0 + nx, y0 + ny}, [2]float64{x1 + nx, y1 + ny},
+		[2]float64{x1 - nx, y1 - ny}, [2]float64{x0 - nx, y0 - ny})
+}
+
+func fixedToFloat(v fixed.Int26_6) float64 { return float64(v) / 64 }
+func floatToFixed(v float64) fixed.Int26_6 { return fixed.Int26_6(math.Round(v * 64)) }
+
+func ruleThickness(st mathStyle) float64 {
+	return max(1, st.size()*0.05)
+}
+
+func emptyBox(kind atomKind) mathBox {
+	return mathBox{kind: kind, draw: func(draw.Image, float64, float64) {}}
+}
+
+func spaceBox(w float64) mathBox {
+	b := emptyBox(atomOrd)
+	b.w = w
+	return b
+}
+
+// shift 把盒子向上移动 dy 像素
+func shift(b mathBox, dy float64) mathBox {
+	inner := b.draw
+	b.h, b.d = b.h+dy, b.d-dy
+	b.draw = func(dst draw.Image, x, y float64) { inner(dst, x, y-dy) }
+	return b
+}
+
+// centerOnAxis 让盒子在数学轴上垂直居中 (大型运算符、可伸缩的定界符)
+func centerOnAxis(b mathBox, st mathStyle) mathBox {
+	return shift(b, st.size()*mathAxis-(b.h-b.d)/2)
+}
+
+// atomSpacing 返回两个相邻原子之间的间距 (1/18 em)，上下标中只保留运算符两侧的间距
+func atomSpacing(left, right atomKind, script bool) float64 {
+	switch {
+	case left == atomOp && (right == atomOrd || right == atomOp):
+		return 3
+	case right == atomOp && (left == atomOrd || left == atomClose):
+		return 3
+	case script:
+		return 0
+	case left == atomBin || right == atomBin:
+		return 4
+	case left == atomRel && right == atomRel:
+		return 0
+	case left == atomRel || right == atomRel:
+		return 5
+	case left == atomPunct:
+		return 3
+	case (left == atomInner || right == atomInner) && left != atomOpen && right != atomClose:
+		return 3
+	}
+	return 0
+}
+
+// hlist 水平排列盒子，按原子类型插入间距；没有左操作数的二元运算符 (如负号) 按普通符号处理
+func hlist(boxes []mathBox, st mathStyle, kind atomKind) mathBox {
+	for i := range boxes {
+		if boxes[i].kind != atomBin {
+			continue
+		}
+		prev := atomOp
+		if i > 0 {
+			prev = boxes[i-1].kind
+		}
+		next := atomClose
+		if i+1 < len(boxes) {
+			next = boxes[i+1].kind
+		} else {
+			next = atomRel
+		}
+		if prev == atomBin || prev == atomOp || prev == atomRel || prev == atomOpen || prev == atomPunct ||
+			next == atomRel || next == atomClose || next == atomPunct {
+			boxes[i].kind = atomOrd
+		}
+	}
+
+	mu := st.size() / 18
+	out := emptyBox(kind)
+	offsets := make([]float64, len(boxes))
+	for i, b := range boxes {
+		if i > 0 {
+			out.w += atomSpacing(boxes[i-1].kind, b.kind, st.level > 0) * mu
+		}
+		offsets[i] = out.w
+		out.w += b.w
+		out.h = max(out.h, b.h)
+		out.d = max(out.d, b.d)
+	}
+	out.draw = func(dst draw.Image, x, y float64) {
+		for i, b := range boxes {
+			b.draw(dst, x+offsets[i], y)
+		}
+	}
+	return out
+}
+
+// vstack 把上方盒子 (基线距离 up) 与下方盒子 (基线距离 down) 与中间的盒子水平居中叠放
+func vstack(base mathBox, over *mathBox, overShift float64, under *mathBox, underShift float64) mathBox {
+	out := base
+	out.w = base.w
+	if over != nil {
+		out.w = max(out.w, over.w)
+		out.h = max(out.h, overShift+over.h)
+	}
+	if under != nil {
+		out.w = max(out.w, under.w)
+		out.d = max(out.d, underShift+under.d)
+	}
+	out.draw = func(dst draw.Image, x, y float64) {
+		base.draw(dst, x+(out.w-base.w)/2, y)
+		if over != nil {
+			over.draw(dst, x+(out.w-over.w)/2, y-overShift)
+		}
+		if under != nil {
+			under.draw(dst, x+(out.w-under.w)/2, y+underShift)
+		}
+	}
+	return out
+}
+
+// mathLetter 把字母映射到对应数学字体的 Unicode 字符
+func mathLetter(r rune, f mathFont) rune {
+	upper := r >= 'A' && r <= 'Z'
+	lower := r >= 'a' && r <= 'z'
+	digit := r >= '0' && r <= '9'
+	switch f {
+	case mathItalic:
+		switch {
+		case r == 'h':
+			return 'ℎ'
+		case upper:
+			return 0x1D434 + r - 'A'
+		case lower:
+			return 0x1D44E + r - 'a'
+		case r >= 'α' && r <= 'ω':
+			return 0x1D6FC + r - 'α'
+		}
+		if v, ok := mathItalicGreekVariants[r]; ok {
+			return v
+		}
+	case mathBold:
+		switch {
+		case upper:
+			return 0x1D400 + r - 'A'
+		case lower:
+			return 0x1D41A + r - 'a'
+		case digit:
+			return 0x1D7CE + r - '0'
+		}
+	case mathBlackboard:
+		if v, ok := map[rune]rune{'C': 'ℂ', 'H': 'ℍ', 'N': 'ℕ', 'P': 'ℙ', 'Q': 'ℚ', 'R': 'ℝ', 'Z': 'ℤ'}[r]; ok {
+			return v
+		}
+		switch {
+		case upper:
+			return 0x1D538 + r - 'A'
+		case lower:
+			return 0x1D552 + r - 'a'
+		case digit:
+			return 0x1D7D8 + r - '0'
+		}
+	case mathCalligraphic:
+		if v, ok := map[rune]rune{'B': 'ℬ', 'E': 'ℰ', 'F': 'ℱ', 'H': 'ℋ', 'I': 'ℐ', 'L': 'ℒ', 'M': 'ℳ', 'R': 'ℛ'}[r]; ok {
+			return v
+		}
+		if upper {
+			return 0x1D49C + r - 'A'
+		}
+	}
+	return r
+}
+
+// tokenizeTeX 把公式拆分为命令、单个字符与空白
+func tokenizeTeX(tex string) []string {
+	runes := []rune(tex)
+	var toks []string
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case r == '\\' && i+1 < len(runes):
+			j := i + 1
+			for j < len(runes) && unicode.IsLetter(runes[j]) && runes[j] < 0x80 {
+				j++
+			}
+			if j == i+1 {
+				j++
+			}
+			toks = append(toks, string(runes[i:j]))
+			i = j
+		case unicode.IsSpace(r):
+			for i < len(runes) && unicode.IsSpace(runes[i]) {
+				i++
+			}
+			toks = append(toks, " ")
+		default:
+			toks = append(toks, string(r))
+			i++
+		}
+	}
+	return toks
+}
+
+// mathParser 递归下降地解析并排版公式
+type mathParser struct {
+	r    *mathRenderer
+	toks []string
+	pos  int
+}
+
+func (p *mathParser) peek() string {
+	for p.pos < len(p.toks) && p.toks[p.pos] == " " {
+		p.pos++
+	}
+	if p.pos == len(p.toks) {
+		return ""
+	}
+	return p.toks[p.pos]
+}
+
+func (p *mathParser) next() string {
+	tok := p.peek()
+	if tok != "" {
+		p.pos++
+	}
+	return tok
+}
+
+func (p *mathParser) expect(tok string) error {
+	if got := p.next(); got != tok {
+		if got == "" {
+			return fmt.Errorf("缺少 %s", tok)
+		}
+		return fmt.Errorf("应为 %s，实际为 %s", tok, got)
+	}
+	return nil
+}
+
+// parseFormula 解析整个公式，公式中的 \\ 把多行公式居中排列
+func (p *mathParser) parseFormula(st mathStyle) (mathBox, error) {
+	return p.parseRows(st, "gathered", "")
+}
+
+// isStop 判断记号是否结束当前的列表
+func isStop(tok string, stops []string) bool {
+	for _, s := range stops {
+		if tok == s {
+			return true
+		}
+	}
+	return false
+}
+
+// parseList 解析直到遇到 stops 中的记号 (不消耗) 或公式结束
+func (p *mathParser) parseList(st mathStyle, kind atomKind, stops ...string) (mathBox, error) {
+	var boxes []mathBox
+	for {
+		tok := p.peek()
+		if tok == "" || isStop(tok, stops) {
+			break
+		}
+		switch tok {
+		case "}", "&", `\\`, `\end`, `\right`:
+			return mathBox{}, fmt.Errorf("意外的 %s", tok)
+		}
+		box, err := p.parseAtom(st)
+		if err != nil {
+			return mathBox{}, err
+		}
+		if box, err = p.parseScripts(box, st); err != nil {
+			return mathBox{}, err
+		}
+		boxes = append(boxes, box)
+	}
+	return hlist(boxes, st, kind), nil
+}
+
+// parseScripts 解析原子后面的上标、下标与撇号
+func (p *mathParser) parseScripts(base mathBox, st mathStyle) (mathBox, error) {
+	var sup, sub []mathBox
+	for {
+		switch p.peek() {
+		case "'":
+			p.next()
+			sup = append(sup, p.r.glyph("′", st.script().size(), atomOrd))
+			continue
+		case "^", "_":
+			tok := p.next()
+			arg, err := p.parseArg(st.script())
+			if err != nil {
+				return mathBox{}, err
+			}
+			if tok == "^" {
+				sup = append(sup, arg)
+			} else {
+				sub = append(sub, arg)
+			}
+			continue
+		}
+		break
+	}
+	if sup == nil && sub == nil {
+		return base, nil
+	}
+	var supBox, subBox *mathBox
+	if sup != nil {
+		b := hlist(sup, st.script(), atomOrd)
+		supBox = &b
+	}
+	if sub != nil {
+		b := hlist(sub, st.script(), atomOrd)
+		subBox = &b
+	}
+	size := st.size()
+	gap := size * 0.12
+	if base.limits && st.display {
+		var overShift, underShift float64
+		if supBox != nil {
+			overShift = base.h + gap + supBox.d
+		}
+		if subBox != nil {
+			underShift = base.d + gap + subBox.h
+		}
+		out := vstack(base, supBox, overShift, subBox, underShift)
+		out.kind = atomOp
+		return out, nil
+	}
+
+	// 上标基线至少高于基线 0.36em，下标基线至少低于基线 0.18em，同时出现时保持间隔
+	supShift := max(base.h-size*0.35, size*0.36)
+	subShift := max(base.d+size*0.1, size*0.18)
+	if supBox != nil && subBox != nil {
+		if space := (supShift - supBox.d) - (subBox.h - subShift); space < size*0.1 {
+			subShift += size*0.1 - space
+		}
+	}
+	if supBox != nil {
+		supShift = max(supShift, supBox.d+size*0.1)
+	}
+	if subBox != nil {
+		subShift = max(subShift, subBox.h-size*0.4)
+	}
+	kern := size * 0.05
+	out := base
+	out.limits = false
+	scriptW := 0.0
+	if supBox != nil {
+		scriptW = max(scriptW, supBox.w)
+		out.h = max(out.h, supShift+supBox.h)
+	}
+	if subBox != nil {
+		scriptW = max(scriptW, subBox.w)
+		out.d = max(out.d, subShift+subBox.d)
+	}
+	out.w = base.w + kern + scriptW
+	out.draw = func(dst draw.Image, x, y float64) {
+		base.draw(dst, x, y)
+		if supBox != nil {
+			supBox.draw(dst, x+base.w+kern, y-supShift)
+		}
+		if subBox != nil {
+			subBox.draw(dst, x+base.w+kern, y+subShift)
+		}
+	}
+	return out, nil
+}
+
+// parseArg 解析命令的参数：花括号中的内容或单个原子
+func (p *mathParser) parseArg(st mathStyle) (mathBox, error) {
+	if p.peek() == "{" {
+		p.next()
+		box, err := p.parseList(st, atomOrd, "}")
+		if err != nil {
+			return mathBox{}, err
+		}
+		return box, p.expect("}")
+	}
+	if p.peek() == "" {
+		return mathBox{}, fmt.Errorf("缺少参数")
+	}
+	return p.parseAtom(st)
+}
+
+// parseRawArg 读取花括号中的原始文字 (\text、\begin 的参数)
+func (p *mathParser) parseRawArg() (string, error) {
+	if err := p.expect("{"); err != nil {
+		return "", err
+	}
+	var sb strings.Builder
+	depth := 0
+	for ; p.pos < len(p.toks); p.pos++ {
+		tok := p.toks[p.pos]
+		switch tok {
+		case "{":
+			depth++
+		case "}":
+			if depth == 0 {
+				p.pos++
+				return sb.String(), nil
+			}
+			depth--
+		}
+		if strings.HasPrefix(tok, `\`) && len(tok) == 2 {
+			tok = tok[1:]
+		}
+		sb.WriteString(tok)
+	}
+	return "", fmt.Errorf("缺少 }")
+}
+
+// parseAtom 解析一个原子 (不含上下标)
+func (p *mathParser) parseAtom(st mathStyle) (mathBox, error) {
+	tok := p.peek()
+	switch tok {
+	case "^", "_", "'":
+		// 没有底的上下标
+		return emptyBox(atomOrd), nil
+	case "{":
+		p.next()
+		box, err := p.parseList(st, atomOrd, "}")
+		if err != nil {
+			return mathBox{}, err
+		}
+		return box, p.expect("}")
+	}
+	p.next()
+	if strings.HasPrefix(tok, `\`) || tok == "~" {
+		return p.parseCommand(tok, st)
+	}
+	return p.symbol([]rune(tok)[0], st), nil
+}
+
+// symbol 排版单个字符
+func (p *mathParser) symbol(r rune, st mathStyle) mathBox {
+	kind := atomOrd
+	text := string(r)
+	switch r {
+	case '+':
+		kind = atomBin
+	case '-', '−':
+		kind, text = atomBin, "−"
+	case '*':
+		kind, text = atomBin, "∗"
+	case '=', '<', '>', ':':
+		kind = atomRel
+	case ',', ';':
+		kind = atomPunct
+	case '(', '[':
+		kind = atomOpen
+	case ')', ']', '!', '?':
+		kind = atomClose
+	default:
+		text = string(mathLetter(r, st.font))
+	}
+	return p.r.glyph(text, st.size(), kind)
+}
+
+// parseCommand 解析以反斜杠开头的命令
+func (p *mathParser) parseCommand(cmd string, st mathStyle) (mathBox, error) {
+	size := st.size()
+	if s, ok := mathSymbols[cmd]; ok {
+		return p.r.glyph(s.text, size, s.kind), nil
+	}
+	if r, ok := mathGreek[cmd]; ok {
+		if unicode.IsLower(r) && st.font == mathItalic {
+			r = mathLetter(r, mathItalic)
+		} else if st.font == mathBold {
+			r = mathLetter(r, mathBold)
+		}
+		return p.r.glyph(string(r), size, atomOrd), nil
+	}
+	if w, ok := mathSpaces[cmd]; ok {
+		return spaceBox(w * size), nil
+	}
+	if op, ok := mathBigOps[cmd]; ok {
+		opSize := size * 1.25
+		if st.display {
+			opSize = size * 1.7
+		}
+		box := centerOnAxis(p.r.glyph(op.text, opSize, atomOp), st)
+		box.limits = op.limits
+		return box, nil
+	}
+	if limits, ok := mathFunctions[cmd]; ok {
+		name := strings.TrimPrefix(cmd, `\`)
+		switch name {
+		case "argmax", "argmin":
+			name = "arg " + name[3:]
+		case "limsup", "liminf":
+			name = "lim " + name[3:]
+		}
+		box := p.r.glyph(name, size, atomOp)
+		box.limits = limits
+		return box, nil
+	}
+	if accent, ok := mathAccents[cmd]; ok {
+		base, err := p.parseArg(st)
+		if err != nil {
+			return mathBox{}, err
+		}
+		accentSize := size
+		if cmd == `\vec` {
+			accentSize = size * 0.7
+		}
+		acc := p.r.ink(accent, accentSize, atomOrd)
+		gap := size * 0.05
+		// 重音字形的轮廓底部放在底的上方
+		return vstack(base, &acc, base.h+gap+acc.d, nil, 0), nil
+	}
+
+	switch cmd {
+	case `\frac`, `\dfrac`, `\tfrac`, `\binom`:
+		return p.parseFraction(cmd, st)
+	case `\sqrt`:
+		return p.parseSqrt(st)
+	case `\left`:
+		return p.parseLeftRight(st)
+	case `\begin`:
+		name, err := p.parseRawArg()
+		if err != nil {
+			return mathBox{}, err
+		}
+		if _, ok := mathEnvDelims[name]; !ok {
+			return mathBox{}, fmt.Errorf("不支持的环境 %s", name)
+		}
+		if name == "array" && p.peek() == "{" {
+			// 忽略列格式
+			if _, err := p.parseRawArg(); err != nil {
+				return mathBox{}, err
+			}
+		}
+		return p.parseEnvironment(name, st)
+	case `\text`, `\textrm`, `\mbox`, `\textit`, `\textbf`:
+		text, err := p.parseRawArg()
+		if err != nil {
+			return mathBox{}, err
+		}
+		return p.r.glyph(text, size, atomOrd), nil
+	case `\operatorname`:
+		text, err := p.parseRawArg()
+		if err != nil {
+			return mathBox{}, err
+		}
+		return p.r.glyph(text, size, atomOp), nil
+	case `\mathrm`, `\mathit`, `\mathbf`, `\boldsymbol`, `\mathbb`, `\mathcal`:
+		inner := st
+		inner.font = map[string]mathFont{
+			`\mathrm`: mathRoman, `\mathit`: mathItalic, `\mathbf`: mathBold, `\boldsymbol`: mathBold,
+			`\mathbb`: mathBlackboard, `\mathcal`: mathCalligraphic,
+		}[cmd]
+		return p.parseArg(inner)
+	case `\overline`, `\bar`, `\underline`:
+		base, err := p.parseArg(st)
+		if err != nil {
+			return mathBox{}, err
+		}
+		t := ruleThickness(st)
+		gap := size * 0.08
+		out := base
+		if cmd == `\underline` {
+			out.d = base.d + gap + t
+		} else {
+			out.h = base.h + gap + t
+		}
+		out.draw = func(dst draw.Image, x, y float64) {
+			base.draw(dst, x, y)
+			if cmd == `\underline` {
+				p.r.rule(dst, x, x+base.w, y+base.d+gap+t/2, t)
+			} else {
+				p.r.rule(dst, x, x+base.w, y-base.h-gap-t/2, t)
+			}
+		}
+		return out, nil
+	case `\displaystyle`:
+		st.display = true
+		return p.parseList(st, atomOrd, "}", "&", `\\`, `\end`, `\right`)
+	case `\limits`, `\nolimits`, `\big`, `\Big`, `\bigg`, `\Bigg`, `\bigl`, `\bigr`, `\Bigl`, `\Bigr`:
+		// 只影响大小的命令直接忽略
+		return emptyBox(atomOrd), nil
+	}
+	return mathBox{}, fmt.Errorf("不支持的命令 %s", cmd)
+}
+
+// parseFraction 排版分式与二项式系数
+func (p *mathParser) parseFraction(cmd string, st mathStyle) (mathBox, error) {
+	switch cmd {
+	case `\dfrac`:
+		st.display = true
+	case `\tfrac`:
+		st.display = false
+	}
+	inner := st.fraction()
+	num, err := p.parseArg(inner)
+	if err != nil {
+		return mathBox{}, err
+	}
+	den, err := p.parseArg(inner)
+	if err != nil {
+		return mathBox{}, err
+	}
+	size := st.size()
+	axis := size * mathAxis
+	t := ruleThickness(st)
+	if cmd == `\binom` {
+		t = 0
+	}
+	gap := max(t, size*0.1)
+	pad := size * 0.1
+	w := max(num.w, den.w) + 2*pad
+	numShift := axis + t/2 + gap + num.d
+	denShift := -axis + t/2 + gap + den.h
+	frac := mathBox{
+		w:    w,
+		h:    numShift + num.h,
+		d:    denShift + den.d,
+		kind: atomInner,
+		draw: func(dst draw.Image, x, y float64) {
+			num.draw(dst, x+(w-num.w)/2, y-numShift)
+			den.draw(dst, x+(w-den.w)/2, y+denShift)
+			if t > 0 {
+				p.r.rule(dst, x+pad/2, x+w-pad/2, y-axis, t)
+			}
+		},
+	}
+	if cmd == `\binom` {
+		return p.delimited("(", frac, ")", st), nil
+	}
+	return frac, nil
+}
+
+// parseSqrt 排版根式，可选的 [n] 是根指数
+func (p *mathParser) parseSqrt(st mathStyle) (mathBox, error) {
+	var index *mathBox
+	if p.peek() == "[" {
+		p.next()
+		idx := st.script().script()
+		b, err := p.parseList(idx, atomOrd, "]")
+		if err != nil {
+			return mathBox{}, err
+		}
+		if err := p.expect("]"); err != nil {
+			return mathBox{}, err
+		}
+		index = &b
+	}
+	body, err := p.parseArg(st)
+	if err != nil {
+		return mathBox{}, err
+	}
+	size := st.size()
+	t := ruleThickness(st)
+	gap := size * 0.12
+	signW := size * 0.6
+	h := max(body.h, size*0.7) + gap + t
+	d := max(body.d, size*0.2)
+	lead := 0.0
+	if index != nil {
+		lead = max(0, index.w-signW*0.45)
+	}
+	out := mathBox{
+		w:    lead + signW + body.w + size*0.1,
+		h:    h,
+		d:    d,
+		kind: atomOrd,
+		draw: func(dst draw.Image, x, y float64) {
+			x0 := x + lead
+			total := h + d
+			hookY := y + d - min(total*0.45, size*0.55)
+			// 短的上升笔画、粗的下降笔画、细的上升笔画与顶部横线
+			p.r.stroke(dst, x0, hookY+size*0.08, x0+signW*0.2, hookY, t)
+			p.r.stroke(dst, x0+signW*0.2, hookY, x0+signW*0.5, y+d, t*2)
+			p.r.stroke(dst, x0+signW*0.5, y+d, x0+signW, y-h+t/2, t)
+			p.r.rule(dst, x0+signW-t/2, x0+signW+body.w+size*0.1, y-h+t/2, t)
+			body.draw(dst, x0+signW+size*0.05, y)
+			if index != nil {
+				index.draw(dst, x, hookY-size*0.08-index.d)
+			}
+		},
+	}
+	if index != nil {
+		// 根指数的基线在根号短笔画的上方
+		hook := min((h+d)*0.45, size*0.55) - d
+		out.h = max(out.h, hook+size*0.08+index.d+index.h)
+	}
+	return out, nil
+}
+
+// readDelimiter 读取 \left、\right 后面的定界符，"." 表示不显示
+func (p *mathParser) readDelimiter() (string, error) {
+	tok := p.next()
+	switch {
+	case tok == "":
+		return "", fmt.Errorf("缺少定界符")
+	case tok == ".":
+		return ".", nil
+	case strings.HasPrefix(tok, `\`):
+		if s, ok := mathSymbols[tok]; ok {
+			return s.text, nil
+		}
+		return "", fmt.Errorf("不支持的定界符 %s", tok)
+	}
+	return tok, nil
+}
+
+// parseLeftRight 排版 \left ... \right 定界符，定界符随内容伸缩
+func (p *mathParser) parseLeftRight(st mathStyle) (mathBox, error) {
+	left, err := p.readDelimiter()
+	if err != nil {
+		return mathBox{}, err
+	}
+	body, err := p.parseList(st, atomInner, `\right`)
+	if err != nil {
+		return mathBox{}, err
+	}
+	if err := p.expect(`\right`); err != nil {
+		return mathBox{}, err
+	}
+	right, err := p.readDelimiter()
+	if err != nil {
+		return mathBox{}, err
+	}
+	return p.delimited(left, body, right, st), nil
+}
+
+// delimited 在内容两侧加上与内容同高的定界符
+func (p *mathParser) delimited(left string, body mathBox, right string, st mathStyle) mathBox {
+	size := st.size()
+	axis := size * mathAxis
+	half := max(body.h-axis, body.d+axis)
+	boxes := []mathBox{p.delimiter(left, half, st, atomOpen), body, p.delimiter(right, half, st, atomClose)}
+	out := hlist(boxes, st, atomInner)
+	return out
+}
+
+// delimiter 按需要的半高 (以数学轴为中心) 放大定界符字形
+func (p *mathParser) delimiter(text string, half float64, st mathStyle, kind atomKind) mathBox {
+	size := st.size()
+	if text == "." {
+		return spaceBox(size * 0.1)
+	}
+	g := p.r.glyph(text, size, kind)
+	if ink := g.h + g.d; ink > 0 {
+		if scale := 2 * half * 1.1 / ink; scale > 1 {
+			g = p.r.glyph(text, size*min(scale, 5), kind)
+		}
+	}
+	g = centerOnAxis(g, st)
+	g.kind = kind
+	return g
+}
+
+// parseEnvironment 排版矩阵、分段函数与对齐环境
+func (p *mathParser) parseEnvironment(name string, st mathStyle) (mathBox, error) {
+	inner := st
+	if name != "aligned" && name != "align" && name != "align*" && name != "gathered" && name != "split" {
+		inner.display = false
+	}
+	body, err := p.parseRows(inner, name, `\end`)
+	if err != nil {
+		return mathBox{}, err
+	}
+	if err := p.expect(`\end`); err != nil {
+		return mathBox{}, err
+	}
+	end, err := p.parseRawArg()
+	if err != nil {
+		return mathBox{}, err
+	}
+	if end != name {
+		return mathBox{}, fmt.Errorf("\\begin{%s} 与 \\end{%s} 不匹配", name, end)
+	}
+	delims := mathEnvDelims[name]
+	if delims == [2]string{".", "."} {
+		return body, nil
+	}
+	return p.delimited(delims[0], body, delims[1], st), nil
+}
+
+// parseRows 解析以 & 分列、以 \\ 分行的内容直到 end，并按环境的对齐方式排成表格
+func (p *mathParser) parseRows(st mathStyle, env, end string) (mathBox, error) {
+	var rows [][]mathBox
+	row := []mathBox{}
+	for {
+		cell, err := p.parseList(st, atomOrd, "&", `\\`, end)
+		if err != nil {
+			return mathBox{}, err
+		}
+		row = append(row, cell)
+		tok := p.peek()
+		if tok == "&" {
+			p.next()
+			continue
+		}
+		rows = append(rows, row)
+		row = []mathBox{}
+		if tok == `\\` {
+			p.next()
+			continue
+		}
+		if tok == "" && end != "" {
+			return mathBox{}, fmt.Errorf("缺少 %s", end)
+		}
+		if tok != end {
+			return mathBox{}, fmt.Errorf("意外的 %s", tok)
+		}
+		break
+	}
+	// 末尾的 \\ 不产生空行
+	if n := len(rows); n > 1 && len(rows[n-1]) == 1 && rows[n-1][0].w == 0 {
+		rows = rows[:n-1]
+	}
+	if len(rows) == 1 && len(rows[0]) == 1 {
+		return rows[0][0], nil
+	}
+
+	size := st.size()
+	cols := 0
+	for _, r := range rows {
+		cols = max(cols, len(r))
+	}
+	colW := make([]float64, cols)
+	rowH := make([]float64, len(rows))
+	rowD := make([]float64, len(rows))
+	for i, r := range rows {
+		rowH[i], rowD[i] = size*0.7, size*0.3
+		for j, c := range r {
+			colW[j] = max(colW[j], c.w)
+			rowH[i] = max(rowH[i], c.h)
+			rowD[i] = max(rowD[i], c.d)
+		}
+	}
+	align := func(j int) float64 {
+		switch env {
+		case "cases":
+			return 0
+		case "aligned", "align", "align*", "split":
+			if j%2 == 0 {
+				return 1
+			}
+			return 0
+		}
+		return 0.5
+	}
+	colGap := size
+	switch env {
+	case "aligned", "align", "align*", "split":
+		colGap = 0
+	}
+	rowGap := size * 0.3
+	total := 0.0
+	for i := range rows {
+		total += rowH[i] + rowD[i]
+	}
+	total += rowGap * float64(len(rows)-1)
+	width := 0.0
+	colX := make([]float64, cols)
+	for j, w := range colW {
+		if j > 0 {
+			width += colGap
+			if colGap == 0 && j%2 == 0 {
+				width += size * 2
+			}
+		}
+		colX[j] = width
+		width += w
+	}
+	axis := size * mathAxis
+	return mathBox{
+		w:    width,
+		h:    total/2 + axis,
+		d:    total/2 - axis,
+		kind: atomInner,
+		draw: func(dst draw.Image, x, y float64) {
+			top := y - total/2 - axis
+			for i, r := range rows {
+				baseline := top + rowH[i]
+				for j, c := range r {
+					c.draw(dst, x+colX[j]+(colW[j]-c.w)*align(j), baseline)
+				}
+				top = baseline + rowD[i] + rowGap
+			}
+		},
+	}, nil
+}
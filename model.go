@@ -0,0 +1,266 @@
+package main
+
+// 中间文档模型：processDocument 从 Google Docs 构建出 Document，
+// Markdown 只是其中一种输出 (renderMarkdown)，微信 HTML 通过 buildAST 直接生成 goldmark AST 渲染
+
+// Document 是一篇文章的类型化表示
+type Document struct {
+	Title string
+	// Subtitle 来自文档中的 "副标题" 样式段落，用于生成封面
+	Subtitle string
+	// TabID 与 TabTitle 是文章所在的 Google Docs 标签页
+	TabID    string
+	TabTitle string
+	// Part 是文档在分割点处分成多篇文章时的序号 (从 1 开始)，没有分割时为 0
+	Part      int
+	Blocks    []Block
+	Footnotes []*Footnote
+}
+
+// Block 是块级元素：标题、段落、引用、分隔线、图片、列表、表格
+type Block interface {
+	isBlock()
+}
+
+// Inline 是行内元素：文本、图片、换行、脚注引用
+type Inline interface {
+	isInline()
+}
+
+// Heading 是 1-3 级标题
+type Heading struct {
+	Level   int
+	Inlines []Inline
+}
+
+// Paragraph 是普通段落，单独成段的图片也是一个只包含 Image 的段落
+type Paragraph struct {
+	Inlines []Inline
+	// Align 为 "center"、"right"、"justify" 或空字符串 (左对齐)
+	Align string
+	// Indent 和 FirstLineIndent 是左缩进与首行缩进 (pt)
+	Indent          float64
+	FirstLineIndent float64
+}
+
+// Blockquote 是引用块，由 Google Docs 中连续的缩进段落转换而来
+type Blockquote struct {
+	Blocks []Block
+}
+
+// Callout 是提示框 (提示、注意、警告等)，Kind 决定图标与配色，Title 是显示的标题
+type Callout struct {
+	Kind   string
+	Title  string
+	Blocks []Block
+}
+
+// Divider 是分隔线，由水平线、分节符与分页符转换而来
+type Divider struct{}
+
+// Figure 是单独成段并居中的图片，或带有说明的图片。
+// Number 为 0 时不显示编号，Label 是显示的编号文字 (如 "图 1")
+type Figure struct {
+	Image   *Image
+	Caption []Inline
+	Number  int
+	Label   string
+}
+
+// List 是一个列表，嵌套列表放在父列表项的 Blocks 中
+type List struct {
+	Ordered bool
+	// Checklist 为 true 时是核对清单，列表项显示为 ☑/☐
+	Checklist bool
+	Items     []*ListItem
+}
+
+// ListItem 是列表项，第一个块通常是 Paragraph
+type ListItem struct {
+	Blocks []Block
+	// Checked 是核对清单项的勾选状态
+	Checked bool
+}
+
+// Table 是表格，第一行作为表头
+type Table struct {
+	Rows []*TableRow
+}
+
+type TableRow struct {
+	Cells []*TableCell
+}
+
+type TableCell struct {
+	Inlines []Inline
+}
+
+// TextStyle 是文本的行内样式
+type TextStyle struct {
+	Bold          bool
+	Italic        bool
+	Strikethrough bool
+	Link          string
+	Color         string  // 文字颜色 "#rrggbb"，空字符串表示默认
+	Background    string  // 背景高亮颜色 "#rrggbb"
+	FontSize      float64 // 字号 (pt)，0 表示默认
+	Underline     bool
+	SmallCaps     bool
+	Baseline      string // "SUPERSCRIPT"、"SUBSCRIPT" 或空字符串
+}
+
+// Text 是一段样式相同的文本
+type Text struct {
+	Text  string
+	Style TextStyle
+}
+
+// Image 是文档中的内嵌图片，Title 与 Description 来自 Google Docs 的替代文字设置
+type Image struct {
+	ObjectID    string
+	URL         string
+	Alt         string
+	Title       string
+	Description string
+
+	// SourceURL 是 Google Docs 提供的原图地址 (短期有效)，Crop 与 Rotation 描述作者在文档中做的裁剪和旋转
+	SourceURL string
+	Crop      ImageCrop
+	Rotation  float64 // 顺时针旋转角度 (弧度)
+	// WidthPercent 是图片显示宽度占页面正文宽度的百分比，0 表示未知
+	WidthPercent float64
+	// LocalPath 是下载并处理后的图片文件路径
+	LocalPath string
+	// NoWatermark 为 true 时不加水印，由替代文字中的 [nowatermark] 标记设置
+	NoWatermark bool
+	// Data 是本地生成的图片 (如二维码)，不为空时不再从 SourceURL 下载
+	Data []byte
+	// Formula 不为空时图片是由 LaTeX 公式渲染的，按正文字号缩放显示
+	Formula *Formula
+}
+
+// Formula 是渲染为图片的 LaTeX 公式
+type Formula struct {
+	TeX string
+	// Display 为 true 时是独立成段的公式 ($$...$$)
+	Display bool
+	// Width 是图片宽度，Height 与 Depth 是图片在文字基线以上与以下的高度 (em)
+	Width, Height, Depth float64
+}
+
+// ImageCrop 是裁剪比例，各边偏移为原图宽/高的比例，Angle 为裁剪框的顺时针旋转角度 (弧度)
+type ImageCrop struct {
+	Left, Right, Top, Bottom float64
+	Angle                    float64
+}
+
+func (c ImageCrop) isZero() bool {
+	return c == ImageCrop{}
+}
+
+// LineBreak 是段落内的手动换行 (Google Docs 中的 Shift+Enter)
+type LineBreak struct{}
+
+// FootnoteRef 是正文中对脚注的引用，Number 从 1 开始
+type FootnoteRef struct {
+	ID     string
+	Number int
+}
+
+// Footnote 是脚注内容
+type Footnote struct {
+	ID     string
+	Number int
+	Blocks []Block
+}
+
+func (*Heading) isBlock()    {}
+func (*Paragraph) isBlock()  {}
+func (*Blockquote) isBlock() {}
+func (*Callout) isBlock()    {}
+func (*Divider) isBlock()    {}
+func (*Figure) isBlock()     {}
+func (*List) isBlock()       {}
+func (*Table) isBlock()      {}
+
+func (*Text) isInline()        {}
+func (*Image) isInline()       {}
+func (*LineBreak) isInline()   {}
+func (*FootnoteRef) isInline() {}
+
+// forEachImage 遍历文档中的所有图片 (包括列表、表格、引用与脚注中的图片)
+func forEachImage(d *Document, fn func(img *Image)) {
+	var inlines func([]Inline)
+	var blocks func([]Block)
+	inlines = func(list []Inline) {
+		for _, in := range list {
+			if img, ok := in.(*Image); ok {
+				fn(img)
+			}
+		}
+	}
+	blocks = func(list []Block) {
+		for _, block := range list {
+			switch v := block.(type) {
+			case *Heading:
+				inlines(v.Inlines)
+			case *Paragraph:
+				inlines(v.Inlines)
+			case *Blockquote:
+				blocks(v.Blocks)
+			case *Callout:
+				blocks(v.Blocks)
+			case *Figure:
+				fn(v.Image)
+				inlines(v.Caption)
+			case *List:
+				for _, item := range v.Items {
+					blocks(item.Blocks)
+				}
+			case *Table:
+				for _, row := range v.Rows {
+					for _, cell := range row.Cells {
+						inlines(cell.Inlines)
+					}
+				}
+			}
+		}
+	}
+	blocks(d.Blocks)
+	for _, fn := range d.Footnotes {
+		blocks(fn.Blocks)
+	}
+}
+
+// mergeTexts 合并样式相同的相邻文本，Google Docs 经常把同一段粗体拆成多个 TextRun
+func mergeTexts(inlines []Inline) []Inline {
+	var merged []Inline
+	for _, in := range inlines {
+		t, ok := in.(*Text)
+		if ok && t.Text == "" {
+			continue
+		}
+		if n := len(merged); ok && n > 0 {
+			if prev, ok := merged[n-1].(*Text); ok && prev.Style == t.Style {
+				merged[n-1] = &Text{Text: prev.Text + t.Text, Style: t.Style}
+				continue
+			}
+		}
+		merged = append(merged, in)
+	}
+	return merged
+}
+
+// plainText 返回行内元素中的纯文本
+func plainText(inlines []Inline) string {
+	var s string
+	for _, in := range inlines {
+		switch v := in.(type) {
+		case *Text:
+			s += v.Text
+		case *LineBreak:
+			s += "\n"
+		}
+	}
+	return s
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"sort"
+	"strings"
+	"unicode/utf16"
+
+	"google.golang.org/api/docs/v1"
+)
+
+// 作者在 Google Docs 中用命名范围 (插入 → 书签旁的 "命名范围"，或 Apps Script) 标记的发布指令。
+// Docs API 不返回书签的位置，所以分割点同样使用命名范围 "cut"：每个 cut 范围的起点开始新的一篇文章
+const (
+	rangeWeChatOnly = "wechat-only"
+	rangeWebOnly    = "web-only"
+	rangeHidden     = "hidden"
+	rangeCut        = "cut"
+)
+
+// 输出目标，决定 wechat-only 与 web-only 范围是否保留
+var outputTargets = map[string]bool{
+	"wechat": true,
+	"web":    true,
+}
+
+// indexSpan 是 UTF-16 下标区间 [Start, End)
+type indexSpan struct {
+	Start, End int64
+}
+
+// publishRanges 是文档中需要隐藏的内容 (按正文或脚注分段) 与分割点
+type publishRanges struct {
+	hidden map[string][]indexSpan
+	cuts   []int64
+}
+
+// newPublishRanges 读取文档中的命名范围：hidden 总是隐藏，wechat-only 与 web-only 只在对应的输出目标中保留
+func newPublishRanges(doc *docs.Document, target string) *publishRanges {
+	p := &publishRanges{hidden: map[string][]indexSpan{}}
+	for name, group := range doc.NamedRanges {
+		name = strings.ToLower(strings.TrimSpace(name))
+		hide := name == rangeHidden ||
+			(name == rangeWeChatOnly && target != "wechat") ||
+			(name == rangeWebOnly && target != "web")
+		for _, nr := range group.NamedRanges {
+			for _, r := range nr.Ranges {
+				switch {
+				case hide:
+					p.hidden[r.SegmentId] = append(p.hidden[r.SegmentId], indexSpan{r.StartIndex, r.EndIndex})
+				case name == rangeCut && r.SegmentId == "":
+					p.cuts = append(p.cuts, r.StartIndex)
+				}
+			}
+		}
+	}
+	for segment, spans := range p.hidden {
+		p.hidden[segment] = mergeSpans(spans)
+	}
+	sort.Slice(p.cuts, func(i, j int) bool { return p.cuts[i] < p.cuts[j] })
+	return p
+}
+
+// mergeSpans 排序并合并重叠或相邻的区间
+func mergeSpans(spans []indexSpan) []indexSpan {
+	sort.Slice(spans, func(i, j int) bool { return spans[i].Start < spans[j].Start })
+	var merged []indexSpan
+	for _, s := range spans {
+		if n := len(merged); n > 0 && s.Start <= merged[n-1].End {
+			merged[n-1].End = max(merged[n-1].End, s.End)
+			continue
+		}
+		merged = append(merged, s)
+	}
+	return merged
+}
+
+// isHidden 判断 [start, end) 是否整个位于隐藏范围内
+func (p *publishRanges) isHidden(segment string, start, end int64) bool {
+	for _, s := range p.hidden[segment] {
+		if s.Start <= start && end <= s.End {
+			return true
+		}
+	}
+	return false
+}
+
+// visibleText 去掉文本中位于隐藏范围内的部分，start 是文本在分段中的起始下标
+func (p *publishRanges) visibleText(segment string, start int64, text string) string {
+	if len(p.hidden[segment]) == 0 {
+		return text
+	}
+	units := utf16.Encode([]rune(text))
+	visible := units[:0:0]
+	for i, u := range units {
+		if idx := start + int64(i); !p.isHidden(segment, idx, idx+1) {
+			visible = append(visible, u)
+		}
+	}
+	if len(visible) == len(units) {
+		return text
+	}
+	return string(utf16.Decode(visible))
+}
+
+// cutBefore 判断是否需要在结束于 end 的元素之前开始新的一篇文章，每个分割点只使用一次
+func (p *publishRanges) cutBefore(end int64) bool {
+	cut := false
+	for len(p.cuts) > 0 && p.cuts[0] < end {
+		cut = true
+		p.cuts = p.cuts[1:]
+	}
+	return cut
+}
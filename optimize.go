@@ -0,0 +1,114 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+
+	"golang.org/x/image/draw"
+)
+
+// 超出字节预算时依次尝试的 JPEG 质量下限与每轮缩小的比例
+const (
+	minJPEGQuality  = 50
+	downscaleFactor = 0.8
+)
+
+// optimizeImage 把图片转换为微信接受的 jpg/png，缩放到最大宽度以内并压缩到字节预算以内。
+// 重新编码不会写入 EXIF 等元数据。非 JPEG 来源的图片 (截图、WebP、GIF) 优先输出无损的 PNG，
+// PNG 超出预算时改为 JPEG (透明区域铺白色背景)；JPEG 依次降低质量，仍然超出时继续缩小
+func optimizeImage(src image.Image, format string, cfg ImageConfig) (data []byte, ext string, size image.Point, err error) {
+	img := resizeToWidth(src, cfg.MaxWidth)
+	if format != "jpeg" {
+		data, err = encodePNG(img)
+		if err != nil {
+			return nil, "", image.Point{}, err
+		}
+		if len(data) <= cfg.MaxBytes {
+			return data, ".png", img.Bounds().Size(), nil
+		}
+		if !isOpaque(img) {
+			img = flatten(img, color.White)
+		}
+	}
+
+	for {
+		for quality := cfg.Quality; ; quality -= 10 {
+			if quality < minJPEGQuality {
+				quality = minJPEGQuality
+			}
+			data, err = encodeJPEG(img, quality)
+			if err != nil {
+				return nil, "", image.Point{}, err
+			}
+			if len(data) <= cfg.MaxBytes || quality == minJPEGQuality {
+				break
+			}
+		}
+		b := img.Bounds()
+		if len(data) <= cfg.MaxBytes || b.Dx() <= 1 || b.Dy() <= 1 {
+			return data, ".jpg", b.Size(), nil
+		}
+		img = resizeToWidth(img, int(float64(b.Dx())*downscaleFactor))
+	}
+}
+
+// resizeToWidth 把宽度超过 maxWidth 的图片等比缩小
+func resizeToWidth(src image.Image, maxWidth int) image.Image {
+	b := src.Bounds()
+	if b.Dx() <= maxWidth {
+		return src
+	}
+	height := max(1, b.Dy()*maxWidth/b.Dx())
+	dst := image.NewRGBA(image.Rect(0, 0, maxWidth, height))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), src, b, draw.Src, nil)
+	return dst
+}
+
+// isOpaque 判断图片是否没有透明像素，JPEG 解码结果总是不透明的
+func isOpaque(img image.Image) bool {
+	if o, ok := img.(interface{ Opaque() bool }); ok {
+		return o.Opaque()
+	}
+	return false
+}
+
+// flatten 把图片铺在纯色背景上，去掉透明通道
+func flatten(src image.Image, bg color.Color) image.Image {
+	b := src.Bounds()
+	dst := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(dst, dst.Bounds(), image.NewUniform(bg), image.Point{}, draw.Src)
+	draw.Draw(dst, dst.Bounds(), src, b.Min, draw.Over)
+	return dst
+}
+
+func encodePNG(img image.Image) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := png.Encoder{CompressionLevel: png.BestCompression}
+	if err := enc.Encode(&buf, img); err != nil {
+		return nil, fmt.Errorf("无法编码 PNG: %v", err)
+	}
+	return buf.Bytes(), nil
+}
+
+func encodeJPEG(img image.Image, quality int) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: quality}); err != nil {
+		return nil, fmt.Errorf("无法编码 JPEG: %v", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// formatBytes 把字节数格式化为便于阅读的 KB/MB
+func formatBytes(n int) string {
+	switch {
+	case n >= 1<<20:
+		return fmt.Sprintf("%.1fMB", float64(n)/(1<<20))
+	case n >= 1<<10:
+		return fmt.Sprintf("%.0fKB", float64(n)/(1<<10))
+	}
+	return fmt.Sprintf("%dB", n)
+}
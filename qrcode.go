@@ -0,0 +1,64 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"rsc.io/qr"
+)
+
+// 单独成段的 {{qrcode <URL> [说明]}} 短代码生成二维码图片，说明可以用引号括起来
+var reQRShortcode = regexp.MustCompile(`^\{\{\s*qrcode\s+(\S+)(?:\s+(.*?))?\s*\}\}$`)
+
+// 二维码图片的目标边长 (像素) 与显示宽度占正文宽度的百分比
+const (
+	qrCodePixels       = 360
+	qrCodeWidthPercent = 40
+)
+
+// parseQRShortcode 解析二维码短代码，返回链接与说明
+func parseQRShortcode(text string) (url, caption string, ok bool) {
+	m := reQRShortcode.FindStringSubmatch(strings.TrimSpace(text))
+	if m == nil {
+		return "", "", false
+	}
+	caption = strings.TrimSpace(m[2])
+	for _, q := range []string{`""`, "“”", "''", "‘’"} {
+		open, close := string([]rune(q)[0]), string([]rune(q)[1])
+		if strings.HasPrefix(caption, open) && strings.HasSuffix(caption, close) && len(caption) > len(open)+len(close) {
+			caption = strings.TrimSpace(caption[len(open) : len(caption)-len(close)])
+			break
+		}
+	}
+	return m[1], caption, true
+}
+
+// qrCodeFigure 离线生成二维码 PNG，返回居中显示的 Figure。
+// 图片数据放在 Image.Data 中，之后与文档中的图片一样经过优化与上传
+func qrCodeFigure(url, caption string) (*Figure, error) {
+	code, err := qr.Encode(url, qr.M)
+	if err != nil {
+		return nil, fmt.Errorf("无法生成二维码: %v", err)
+	}
+	// PNG 四周各留 4 个模块的空白
+	code.Scale = max(1, qrCodePixels/(code.Size+8))
+
+	alt := caption
+	if alt == "" {
+		alt = "二维码: " + url
+	}
+	id := "qrcode-" + sha256Hex([]byte(url))[:12]
+	fig := &Figure{Image: &Image{
+		ObjectID:     id,
+		URL:          placeholderImageURL(id, ".png"),
+		Alt:          alt,
+		Data:         code.PNG(),
+		WidthPercent: qrCodeWidthPercent,
+		NoWatermark:  true,
+	}}
+	if caption != "" {
+		fig.Caption = []Inline{&Text{Text: caption}}
+	}
+	return fig, nil
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"google.golang.org/api/docs/v1"
+)
+
+// suggestionsViewModes 把 --suggestions 的取值映射到 Docs API 的 SuggestionsViewMode。
+// fail 使用内联模式读取建议，发现未处理的建议时拒绝转换
+var suggestionsViewModes = map[string]string{
+	"accept": "PREVIEW_SUGGESTIONS_ACCEPTED",
+	"reject": "PREVIEW_WITHOUT_SUGGESTIONS",
+	"fail":   "SUGGESTIONS_INLINE",
+}
+
+// 报错时最多列出的建议数量
+const maxReportedSuggestions = 5
+
+// suggestion 是一处未处理的建议修改
+type suggestion struct {
+	Insert bool
+	Text   string
+}
+
+// findSuggestions 查找要转换的标签页中未处理的建议插入与删除 (正文与脚注)，
+// 其他标签页中的建议不影响本次转换
+func findSuggestions(tabs []*docTab) []suggestion {
+	var found []suggestion
+	add := func(insertions, deletions []string, text string) {
+		text = strings.TrimSpace(text)
+		if len(insertions) > 0 {
+			found = append(found, suggestion{Insert: true, Text: text})
+		}
+		if len(deletions) > 0 {
+			found = append(found, suggestion{Text: text})
+		}
+	}
+
+	var walk func(content []*docs.StructuralElement)
+	walk = func(content []*docs.StructuralElement) {
+		for _, c := range content {
+			switch {
+			case c.Paragraph != nil:
+				for _, elem := range c.Paragraph.Elements {
+					switch {
+					case elem.TextRun != nil:
+						add(elem.TextRun.SuggestedInsertionIds, elem.TextRun.SuggestedDeletionIds, elem.TextRun.Content)
+					case elem.InlineObjectElement != nil:
+						add(elem.InlineObjectElement.SuggestedInsertionIds, elem.InlineObjectElement.SuggestedDeletionIds, "[图片]")
+					case elem.FootnoteReference != nil:
+						add(elem.FootnoteReference.SuggestedInsertionIds, elem.FootnoteReference.SuggestedDeletionIds, "[脚注]")
+					case elem.RichLink != nil:
+						add(elem.RichLink.SuggestedInsertionIds, elem.RichLink.SuggestedDeletionIds, "[链接]")
+					case elem.Person != nil:
+						add(elem.Person.SuggestedInsertionIds, elem.Person.SuggestedDeletionIds, "[人员]")
+					case elem.DateElement != nil:
+						add(elem.DateElement.SuggestedInsertionIds, elem.DateElement.SuggestedDeletionIds, "[日期]")
+					}
+				}
+			case c.Table != nil:
+				add(c.Table.SuggestedInsertionIds, c.Table.SuggestedDeletionIds, "[表格]")
+				for _, row := range c.Table.TableRows {
+					add(row.SuggestedInsertionIds, row.SuggestedDeletionIds, "[表格行]")
+					for _, cell := range row.TableCells {
+						walk(cell.Content)
+					}
+				}
+			}
+		}
+	}
+	for _, tab := range tabs {
+		if tab.Doc.Body != nil {
+			walk(tab.Doc.Body.Content)
+		}
+		for _, fn := range tab.Doc.Footnotes {
+			walk(fn.Content)
+		}
+	}
+	return found
+}
+
+// suggestionsError 列出未处理的建议，提示作者先在 Google Docs 中接受或拒绝
+func suggestionsError(found []suggestion) error {
+	var lines []string
+	for i, s := range found {
+		if i == maxReportedSuggestions {
+			lines = append(lines, fmt.Sprintf("  ... 以及另外 %d 处", len(found)-i))
+			break
+		}
+		mark := "-"
+		if s.Insert {
+			mark = "+"
+		}
+		lines = append(lines, fmt.Sprintf("  %s %q", mark, s.Text))
+	}
+	return fmt.Errorf("文档中还有 %d 处未处理的建议修改，请先在 Google Docs 中接受或拒绝 (或使用 --suggestions=accept|reject):\n%s",
+		len(found), strings.Join(lines, "\n"))
+}
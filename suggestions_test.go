@@ -0,0 +1,47 @@
+package main
+
+import (
+	"testing"
+
+	"google.golang.org/api/docs/v1"
+)
+
+func suggestedParagraph(text string, insert bool) *docs.StructuralElement {
+	run := &docs.TextRun{Content: text}
+	if insert {
+		run.SuggestedInsertionIds = []string{"suggest.1"}
+	} else {
+		run.SuggestedDeletionIds = []string{"suggest.2"}
+	}
+	return &docs.StructuralElement{Paragraph: &docs.Paragraph{Elements: []*docs.ParagraphElement{
+		{TextRun: &docs.TextRun{Content: "正文"}},
+		{TextRun: run},
+	}}}
+}
+
+func TestFindSuggestionsOnlyInSelectedTabs(t *testing.T) {
+	doc := &docs.Document{Tabs: []*docs.Tab{
+		{TabProperties: &docs.TabProperties{TabId: "t.0", Title: "中文"}, DocumentTab: &docs.DocumentTab{
+			Body: &docs.Body{Content: []*docs.StructuralElement{{Paragraph: &docs.Paragraph{}}}},
+		}},
+		{TabProperties: &docs.TabProperties{TabId: "t.1", Title: "English"}, DocumentTab: &docs.DocumentTab{
+			Body:      &docs.Body{Content: []*docs.StructuralElement{suggestedParagraph(" draft ", true)}},
+			Footnotes: map[string]docs.Footnote{"fn": {Content: []*docs.StructuralElement{suggestedParagraph("old", false)}}},
+		}},
+	}}
+	tabs := documentTabs(doc)
+
+	if found := findSuggestions(tabs[:1]); len(found) != 0 {
+		t.Errorf("未选中的标签页中的建议不应被报告: %v", found)
+	}
+	found := findSuggestions(tabs[1:])
+	if len(found) != 2 {
+		t.Fatalf("找到 %d 处建议, 期望 2: %v", len(found), found)
+	}
+	if !found[0].Insert || found[0].Text != "draft" {
+		t.Errorf("正文中的建议 = %+v", found[0])
+	}
+	if found[1].Insert || found[1].Text != "old" {
+		t.Errorf("脚注中的建议 = %+v", found[1])
+	}
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"google.golang.org/api/docs/v1"
+)
+
+// docTab 是文档中的一个标签页 (包括子标签页)，Doc 是只包含该标签页内容的文档，可以直接交给 buildDocument
+type docTab struct {
+	ID    string
+	Title string
+	Level int
+	Doc   *docs.Document
+}
+
+// documentTabs 按文档中的顺序展开所有标签页，子标签页紧跟在父标签页之后。
+// 没有请求标签页内容 (IncludeTabsContent) 时，整个文档作为唯一的标签页
+func documentTabs(doc *docs.Document) []*docTab {
+	if len(doc.Tabs) == 0 {
+		return []*docTab{{Title: doc.Title, Doc: doc}}
+	}
+	var tabs []*docTab
+	var walk func(list []*docs.Tab, level int)
+	walk = func(list []*docs.Tab, level int) {
+		for _, t := range list {
+			if t.TabProperties != nil && t.DocumentTab != nil {
+				tab := t.DocumentTab
+				tabs = append(tabs, &docTab{
+					ID:    t.TabProperties.TabId,
+					Title: t.TabProperties.Title,
+					Level: level,
+					Doc: &docs.Document{
+						DocumentId:        doc.DocumentId,
+						Title:             doc.Title,
+						Body:              tab.Body,
+						DocumentStyle:     tab.DocumentStyle,
+						Footnotes:         tab.Footnotes,
+						InlineObjects:     tab.InlineObjects,
+						Lists:             tab.Lists,
+						NamedRanges:       tab.NamedRanges,
+						NamedStyles:       tab.NamedStyles,
+						PositionedObjects: tab.PositionedObjects,
+					},
+				})
+			}
+			walk(t.ChildTabs, level+1)
+		}
+	}
+	walk(doc.Tabs, 0)
+	return tabs
+}
+
+// selectTabs 选择要转换的标签页：all 为 true 时返回全部，selector 按标签页 ID 或标题匹配，都为空时返回第一个标签页
+func selectTabs(tabs []*docTab, selector string, all bool) ([]*docTab, error) {
+	if len(tabs) == 0 {
+		return nil, fmt.Errorf("文档中没有可以转换的标签页")
+	}
+	if all {
+		return tabs, nil
+	}
+	if selector == "" {
+		return tabs[:1], nil
+	}
+	for _, tab := range tabs {
+		if tab.ID == selector {
+			return []*docTab{tab}, nil
+		}
+	}
+	for _, tab := range tabs {
+		if strings.EqualFold(strings.TrimSpace(tab.Title), strings.TrimSpace(selector)) {
+			return []*docTab{tab}, nil
+		}
+	}
+	var names []string
+	for _, tab := range tabs {
+		names = append(names, fmt.Sprintf("%s%q (%s)", strings.Repeat("  ", tab.Level), tab.Title, tab.ID))
+	}
+	return nil, fmt.Errorf("找不到标签页 %q，文档中的标签页:\n%s", selector, strings.Join(names, "\n"))
+}
+
+// tabOutputFile 返回标签页对应的输出文件名，文件名中不能使用的字符替换为 "_"
+func tabOutputFile(tab *docTab) string {
+	name := strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`/\:*?"<>|`, r) || r < ' ' {
+			return '_'
+		}
+		return r
+	}, strings.TrimSpace(tab.Title))
+	if name == "" {
+		name = tab.ID
+	}
+	return "output-" + name + ".html"
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"testing"
+
+	"google.golang.org/api/docs/v1"
+)
+
+func TestSelectTabs(t *testing.T) {
+	doc := &docs.Document{Title: "文章", Tabs: []*docs.Tab{
+		{TabProperties: &docs.TabProperties{TabId: "t.0", Title: "中文"}, DocumentTab: &docs.DocumentTab{},
+			ChildTabs: []*docs.Tab{{TabProperties: &docs.TabProperties{TabId: "t.1", Title: "附录"}, DocumentTab: &docs.DocumentTab{}}}},
+		{TabProperties: &docs.TabProperties{TabId: "t.2", Title: "English"}, DocumentTab: &docs.DocumentTab{}},
+	}}
+	tabs := documentTabs(doc)
+
+	tests := []struct {
+		selector string
+		all      bool
+		want     []string
+	}{
+		{"", false, []string{"t.0"}},
+		{"", true, []string{"t.0", "t.1", "t.2"}},
+		{"t.2", false, []string{"t.2"}},
+		{" english ", false, []string{"t.2"}},
+		{"附录", false, []string{"t.1"}},
+	}
+	for _, tt := range tests {
+		got, err := selectTabs(tabs, tt.selector, tt.all)
+		if err != nil {
+			t.Errorf("selectTabs(%q, %v) 出错: %v", tt.selector, tt.all, err)
+			continue
+		}
+		var ids []string
+		for _, tab := range got {
+			ids = append(ids, tab.ID)
+		}
+		if len(ids) != len(tt.want) {
+			t.Errorf("selectTabs(%q, %v) = %v, 期望 %v", tt.selector, tt.all, ids, tt.want)
+			continue
+		}
+		for i := range ids {
+			if ids[i] != tt.want[i] {
+				t.Errorf("selectTabs(%q, %v) = %v, 期望 %v", tt.selector, tt.all, ids, tt.want)
+				break
+			}
+		}
+	}
+
+	if _, err := selectTabs(tabs, "不存在", false); err == nil {
+		t.Error("找不到标签页时应返回错误")
+	}
+}
+
+func TestSelectTabsEmpty(t *testing.T) {
+	// 标签页都不是文档标签页时 documentTabs 返回空列表，不应 panic
+	tabs := documentTabs(&docs.Document{Tabs: []*docs.Tab{{TabProperties: &docs.TabProperties{TabId: "t.0"}}}})
+	for _, all := range []bool{false, true} {
+		if _, err := selectTabs(tabs, "", all); err == nil {
+			t.Errorf("selectTabs(空, all=%v) 应返回错误", all)
+		}
+	}
+}
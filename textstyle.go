@@ -0,0 +1,100 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"google.golang.org/api/docs/v1"
+)
+
+// Google Docs 正文的默认字号 (pt)，对应微信正文的 16px
+const docsBaseFontSize = 11.0
+
+// 文字颜色可映射到的主题色板，映射到正文颜色时视为默认颜色不输出
+var textPalette = []string{colorText, colorPrimary, colorAccent, colorMuted}
+
+// 背景高亮可映射到的主题色板，映射到白色时视为无高亮
+var highlightPalette = []string{"#ffffff", colorHighlight, colorPrimaryLight}
+
+// docsColorHex 把 Google Docs 的颜色转换为 "#rrggbb"，未设置时返回空字符串
+func docsColorHex(c *docs.OptionalColor) string {
+	if c == nil || c.Color == nil || c.Color.RgbColor == nil {
+		return ""
+	}
+	rgb := c.Color.RgbColor
+	to8 := func(v float64) int { return int(math.Round(v * 255)) }
+	return fmt.Sprintf("#%02x%02x%02x", to8(rgb.Red), to8(rgb.Green), to8(rgb.Blue))
+}
+
+func parseHexColor(hex string) (r, g, b int, ok bool) {
+	hex = strings.TrimPrefix(strings.ToLower(hex), "#")
+	if len(hex) == 3 {
+		hex = string([]byte{hex[0], hex[0], hex[1], hex[1], hex[2], hex[2]})
+	}
+	if len(hex) != 6 {
+		return 0, 0, 0, false
+	}
+	if _, err := fmt.Sscanf(hex, "%02x%02x%02x", &r, &g, &b); err != nil {
+		return 0, 0, 0, false
+	}
+	return r, g, b, true
+}
+
+// nearestColor 返回色板中与 hex 在 RGB 空间距离最近的颜色
+func nearestColor(hex string, palette []string) string {
+	r, g, b, ok := parseHexColor(hex)
+	if !ok {
+		return ""
+	}
+	best, bestDist := "", math.MaxFloat64
+	for _, p := range palette {
+		pr, pg, pb, _ := parseHexColor(p)
+		d := math.Pow(float64(r-pr), 2) + math.Pow(float64(g-pg), 2) + math.Pow(float64(b-pb), 2)
+		if d < bestDist {
+			best, bestDist = p, d
+		}
+	}
+	return best
+}
+
+// resolveColor 根据配置决定输出的颜色，返回空字符串表示不输出
+func (c ColorConfig) resolveColor(hex string, background bool) string {
+	if hex == "" || c.Mode == "none" {
+		return ""
+	}
+	for _, allowed := range c.Allowlist {
+		if strings.EqualFold(strings.TrimPrefix(allowed, "#"), strings.TrimPrefix(hex, "#")) {
+			return hex
+		}
+	}
+	if c.Mode != "palette" {
+		return ""
+	}
+	if background {
+		if mapped := nearestColor(hex, highlightPalette); mapped != highlightPalette[0] {
+			return mapped
+		}
+		return ""
+	}
+	if mapped := nearestColor(hex, textPalette); mapped != textPalette[0] {
+		return mapped
+	}
+	return ""
+}
+
+// inlineStyle 根据文本样式生成 <span> 的内联样式，没有需要输出的样式时返回空字符串
+func (c ColorConfig) inlineStyle(style TextStyle) string {
+	var parts []string
+	if color := c.resolveColor(style.Color, false); color != "" {
+		parts = append(parts, "color: "+color+";")
+	}
+	if bg := c.resolveColor(style.Background, true); bg != "" {
+		parts = append(parts, "background-color: "+bg+"; "+styleHighlight)
+	}
+	if !c.IgnoreFontSize && style.FontSize > 0 && style.FontSize != docsBaseFontSize {
+		px := math.Round(style.FontSize / docsBaseFontSize * 16)
+		parts = append(parts, fmt.Sprintf("font-size: %gpx;", px))
+	}
+	return strings.Join(parts, " ")
+}
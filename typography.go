@@ -0,0 +1,294 @@
+package main
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/util"
+)
+
+// cjkLatinSpacer 是插入在中日韩文字与拉丁字母/数字之间的间隔 (细空格)
+const cjkLatinSpacer = "\u2009"
+
+// 与中日韩文字相邻时，半角标点转换为对应的全角标点
+var fullWidthPunct = map[rune]rune{
+	',': '，',
+	'.': '。',
+	'!': '！',
+	'?': '？',
+	':': '：',
+	';': '；',
+	'(': '（',
+	')': '）',
+}
+
+func isCJK(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul)
+}
+
+// isCJKPunct 判断是否为全角 (中日韩) 标点符号
+func isCJKPunct(r rune) bool {
+	return (r >= 0x3000 && r <= 0x303F) || (r >= 0xFF00 && r <= 0xFFEF) || r == '“' || r == '”' || r == '‘' || r == '’' || r == '…'
+}
+
+func isLatinOrDigit(r rune) bool {
+	return r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r))
+}
+
+func isSpace(r rune) bool {
+	return r == ' ' || r == '\t' || r == '\u2009'
+}
+
+// typographer 按顺序处理一个块内的所有文本片段，跨节点保留上下文
+type typographer struct {
+	prev      rune   // 已处理文本的最后一个字符
+	quoteOpen bool   // 是否处于未闭合的双引号中
+	parens    []bool // 未闭合的左括号是否已转换为全角
+	trimSpace bool   // 刚写出全角标点，需要吞掉其后的空格 (可能位于下一个节点)
+}
+
+// process 处理一段文本，next 为该文本之后的第一个字符 (没有时为 0)
+func (t *typographer) process(s string, next rune) string {
+	runes := []rune(s)
+	var b strings.Builder
+	at := func(i int) rune {
+		if i >= len(runes) {
+			return next
+		}
+		return runes[i]
+	}
+	write := func(s string) {
+		b.WriteString(s)
+		r := []rune(s)
+		t.prev = r[len(r)-1]
+	}
+
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		prev := t.prev
+		if t.trimSpace && isSpace(r) {
+			continue
+		}
+		t.trimSpace = false
+
+		// 网址原样保留，其中的标点不转换
+		if n := urlLength(runes, i); n > 0 {
+			if isCJK(prev) {
+				write(cjkLatinSpacer)
+			}
+			write(string(runes[i : i+n]))
+			i += n - 1
+			continue
+		}
+
+		// 1. 省略号: "..." 或 "。。。"
+		if (r == '.' || r == '。') && at(i+1) == r && at(i+2) == r {
+			j := i
+			for j < len(runes) && runes[j] == r {
+				j++
+			}
+			if r == '。' || isCJK(prev) || isCJK(at(j)) {
+				write("……")
+			} else {
+				write("…")
+			}
+			i = j - 1
+			continue
+		}
+
+		// 2. 双引号: 按出现顺序交替转换为左右弯引号
+		if r == '"' {
+			if t.quoteOpen {
+				write("”")
+			} else {
+				write("“")
+			}
+			t.quoteOpen = !t.quoteOpen
+			continue
+		}
+
+		// 3. 与中日韩文字相邻的半角标点转为全角，并吞掉其后的空格
+		if fw, ok := fullWidthPunct[r]; ok {
+			after := at(i + 1)
+			convert := false
+			switch r {
+			case '(':
+				// 括号外侧是中文时使用全角括号，括号内是英文也一样 ("中文(English)" → "中文（English）")；
+				// 紧跟拉丁字母或数字的括号 (如 "f(x)") 保持半角
+				convert = isCJK(after) || isCJK(prev)
+				t.parens = append(t.parens, convert)
+			case ')':
+				if n := len(t.parens); n > 0 {
+					convert = t.parens[n-1]
+					t.parens = t.parens[:n-1]
+				} else {
+					convert = isCJK(prev) || isCJKPunct(prev)
+				}
+			case '.':
+				// 例如 "文件.md" 这类紧跟拉丁字母的情况保持原样
+				convert = isCJK(prev) && !isLatinOrDigit(after)
+			default:
+				// 逗号、冒号等在中文之后，或后面 (跳过空格) 是中文时转为全角，
+				// 如 "文件名是a.md,请查收" → "文件名是a.md，请查收"；数字之间的 "1,000"、"12:30" 不受影响
+				convert = (isCJK(prev) && !isLatinOrDigit(after)) || isCJK(nextNonSpace(runes, i+1, next))
+			}
+			if convert {
+				write(string(fw))
+				t.trimSpace = true
+				continue
+			}
+		}
+
+		// 4. 中日韩文字与拉丁字母/数字之间统一使用细空格
+		if isSpace(r) {
+			j := i
+			for j < len(runes) && isSpace(runes[j]) {
+				j++
+			}
+			after := at(j)
+			if (isCJK(prev) && isLatinOrDigit(after)) || (isLatinOrDigit(prev) && isCJK(after)) {
+				write(cjkLatinSpacer)
+				i = j - 1
+				continue
+			}
+		} else if (isCJK(prev) && isLatinOrDigit(r)) || (isLatinOrDigit(prev) && isCJK(r)) {
+			write(cjkLatinSpacer)
+		}
+
+		write(string(r))
+	}
+	return b.String()
+}
+
+// nextNonSpace 返回从 i 开始的第一个非空白字符，文本中没有时为 next
+func nextNonSpace(runes []rune, i int, next rune) rune {
+	for ; i < len(runes); i++ {
+		if !isSpace(runes[i]) {
+			return runes[i]
+		}
+	}
+	return next
+}
+
+// urlLength 返回从 i 开始的网址 (http://、https://) 的长度，不是网址时返回 0。
+// 网址到空白、中日韩文字或全角标点为止，末尾的半角标点不属于网址
+func urlLength(runes []rune, i int) int {
+	if runes[i] != 'h' {
+		return 0
+	}
+	if rest := string(runes[i:min(i+8, len(runes))]); !strings.HasPrefix(rest, "http://") && !strings.HasPrefix(rest, "https://") {
+		return 0
+	}
+	j := i
+	for j < len(runes) && !isSpace(runes[j]) && !isCJK(runes[j]) && !isCJKPunct(runes[j]) {
+		j++
+	}
+	for j > i && strings.ContainsRune(",.!?:;)", runes[j-1]) {
+		j--
+	}
+	return j - i
+}
+
+// typographyLeaf 是块内参与排版的一个行内叶子节点
+type typographyLeaf struct {
+	node     ast.Node
+	value    string
+	readOnly bool // 代码、自动链接等只作为上下文，不修改
+}
+
+func applyTypography(doc ast.Node, source []byte) {
+	_ = ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering || n.Type() != ast.TypeBlock {
+			return ast.WalkContinue, nil
+		}
+		if first := n.FirstChild(); first == nil || first.Type() != ast.TypeInline {
+			return ast.WalkContinue, nil
+		}
+
+		var leaves []typographyLeaf
+		collectTypographyLeaves(n, source, &leaves)
+		typo := &typographer{}
+		for i, leaf := range leaves {
+			var next rune
+			if i+1 < len(leaves) && leaves[i+1].value != "" {
+				next = []rune(leaves[i+1].value)[0]
+			}
+			if leaf.readOnly {
+				typo.trimSpace = false
+				if leaf.value != "" {
+					r := []rune(leaf.value)
+					typo.prev = r[len(r)-1]
+				}
+				continue
+			}
+			out := typo.process(leaf.value, next)
+			if out != leaf.value {
+				replaceTypographyLeaf(leaf.node, out)
+			}
+		}
+		return ast.WalkSkipChildren, nil
+	})
+}
+
+func collectTypographyLeaves(n ast.Node, source []byte, leaves *[]typographyLeaf) {
+	for c := n.FirstChild(); c != nil; c = c.NextSibling() {
+		switch v := c.(type) {
+		case *ast.Text:
+			value := string(v.Segment.Value(source))
+			*leaves = append(*leaves, typographyLeaf{node: v, value: value, readOnly: v.IsRaw()})
+			if v.SoftLineBreak() || v.HardLineBreak() {
+				// 换行处不跨行传递上下文
+				*leaves = append(*leaves, typographyLeaf{readOnly: true, value: "\n"})
+			}
+		case *ast.String:
+			*leaves = append(*leaves, typographyLeaf{node: v, value: string(v.Value), readOnly: v.IsCode()})
+		case *ast.CodeSpan:
+			*leaves = append(*leaves, typographyLeaf{value: string(nodeText(v, source)), readOnly: true})
+		case *ast.AutoLink:
+			*leaves = append(*leaves, typographyLeaf{value: string(v.Label(source)), readOnly: true})
+		case *ast.RawHTML:
+			*leaves = append(*leaves, typographyLeaf{readOnly: true})
+		default:
+			collectTypographyLeaves(c, source, leaves)
+		}
+	}
+}
+
+// replaceTypographyLeaf 用处理后的文本替换节点内容。
+// ast.Text 引用的是源码片段，因此在其前面插入一个新的 String 节点，
+// 并把原节点清空，以保留其软/硬换行标记
+func replaceTypographyLeaf(n ast.Node, value string) {
+	switch v := n.(type) {
+	case *ast.String:
+		v.Value = []byte(value)
+	case *ast.Text:
+		v.Parent().InsertBefore(v.Parent(), v, ast.NewString([]byte(value)))
+		v.Segment = v.Segment.WithStart(v.Segment.Stop)
+	}
+}
+
+// nodeText 拼接节点下所有文本子节点的内容，行内代码以外的反斜杠转义会被还原
+func nodeText(n ast.Node, source []byte) []byte {
+	_, isCode := n.(*ast.CodeSpan)
+	var buf []byte
+	for c := n.FirstChild(); c != nil; c = c.NextSibling() {
+		switch v := c.(type) {
+		case *ast.Text:
+			value := v.Segment.Value(source)
+			if !isCode && !v.IsRaw() {
+				value = util.UnescapePunctuations(value)
+			}
+			buf = append(buf, value...)
+		case *ast.String:
+			if v.IsRaw() || v.IsCode() {
+				buf = append(buf, v.Value...)
+			} else {
+				buf = append(buf, util.UnescapePunctuations(v.Value)...)
+			}
+		default:
+			buf = append(buf, nodeText(c, source)...)
+		}
+	}
+	return buf
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/yuin/goldmark/ast"
+)
+
+// typeset 用新的 typographer 处理一段独立的文本，细空格显示为 "_" 便于阅读
+func typeset(s string) string {
+	t := &typographer{}
+	out := []rune(t.process(s, 0))
+	for i, r := range out {
+		if r == ' ' {
+			out[i] = '_'
+		}
+	}
+	return string(out)
+}
+
+func TestTypographySpacing(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"使用Go语言", "使用_Go_语言"},
+		{"使用 Go 语言", "使用_Go_语言"},
+		{"共100个", "共_100_个"},
+		{"Go  语言", "Go_语言"},
+		{"纯中文", "纯中文"},
+		{"plain English text", "plain English text"},
+		{"版本v1.2发布", "版本_v1.2_发布"},
+	}
+	for _, tt := range tests {
+		if got := typeset(tt.in); got != tt.want {
+			t.Errorf("typeset(%q) = %q, 期望 %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestTypographyPunctuation(t *testing.T) {
+	tests := []struct{ in, want string }{
+		// 中文之后的半角标点转为全角，并去掉其后的空格
+		{"你好,世界", "你好，世界"},
+		{"你好, 世界", "你好，世界"},
+		{"真的吗?", "真的吗？"},
+		{"注意:这里", "注意：这里"},
+		{"结束.", "结束。"},
+		// 拉丁文字之后、中文之前的逗号等也转为全角；句点不转换 (可能是文件名或缩写)
+		{"文件名是a.md,请查收", "文件名是_a.md，请查收"},
+		{"文件名是a.md, 请查收", "文件名是_a.md，请查收"},
+		{"Note:请注意", "Note：请注意"},
+		{"见README.中文", "见_README.中文"},
+		// 紧跟拉丁字母的标点保持半角
+		{"文件.md", "文件.md"},
+		{"Hello, world", "Hello, world"},
+		// 括号：外侧或内侧是中文时使用全角，左右括号成对转换；右括号与中文之间不加空格
+		{"中文(English)中文", "中文（English）中文"},
+		{"中文(注释)", "中文（注释）"},
+		{"(注释)", "（注释）"},
+		{"函数f(x)的值", "函数_f(x)的值"},
+		{"Go(Golang)语言", "Go(Golang)语言"},
+		// 数字中的标点不受影响
+		{"共1,000元", "共_1,000_元"},
+		{"时间12:30开始", "时间_12:30_开始"},
+		{"圆周率3.14", "圆周率_3.14"},
+		// 引号与省略号
+		{`他说"你好"`, "他说“你好”"},
+		{`"quoted" text`, "“quoted” text"},
+		{"等等...", "等等……"},
+		{"wait...", "wait…"},
+		{"好。。。", "好……"},
+		// 网址原样保留，末尾的标点不属于网址
+		{"访问https://example.com/a,b?c=1:2,谢谢", "访问_https://example.com/a,b?c=1:2，谢谢"},
+		{"见http://a.cn.", "见_http://a.cn."},
+		{"链接https://a.cn是官网", "链接_https://a.cn_是官网"},
+	}
+	for _, tt := range tests {
+		if got := typeset(tt.in); got != tt.want {
+			t.Errorf("typeset(%q) = %q, 期望 %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestApplyTypographySkipsCode(t *testing.T) {
+	para := ast.NewParagraph()
+	before := ast.NewString([]byte("调用"))
+	code := ast.NewCodeSpan()
+	codeText := ast.NewString([]byte("fmt.Println(a,b)"))
+	codeText.SetCode(true)
+	code.AppendChild(code, codeText)
+	after := ast.NewString([]byte("打印,结束"))
+	para.AppendChild(para, before)
+	para.AppendChild(para, code)
+	para.AppendChild(para, after)
+	doc := ast.NewDocument()
+	doc.AppendChild(doc, para)
+
+	applyTypography(doc, nil)
+
+	if got := string(codeText.Value); got != "fmt.Println(a,b)" {
+		t.Errorf("行内代码被修改: %q", got)
+	}
+	// 代码两侧的文本照常处理，但不在代码边界上插入空格
+	if got := string(before.Value); got != "调用" {
+		t.Errorf("代码之前的文本 = %q", got)
+	}
+	if got := string(after.Value); got != "打印，结束" {
+		t.Errorf("代码之后的文本 = %q", got)
+	}
+}
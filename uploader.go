@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ImageUploader 把处理后的图片上传到图床，返回可公开访问的图片地址
+type ImageUploader interface {
+	// Name 是图床的名称，用于日志
+	Name() string
+	// Target 唯一标识上传的目的地 (图床、存储桶与访问地址)，作为图片缓存的键
+	Target() string
+	Upload(ctx context.Context, key string, data []byte, contentType string) (string, error)
+}
+
+// newUploader 根据配置创建图床，Provider 为空时不上传，图片地址保留为占位符。
+// wechat 图床使用公众号的开发者凭证
+func newUploader(cfg UploadConfig, wechat WeChatConfig) (ImageUploader, error) {
+	client := &http.Client{Timeout: 60 * time.Second}
+	switch cfg.Provider {
+	case "":
+		return nil, nil
+	case "local":
+		if cfg.Local.Dir == "" || cfg.Local.BaseURL == "" {
+			return nil, fmt.Errorf("local 图床需要配置 dir 与 base_url")
+		}
+		return &localUploader{cfg: cfg.Local}, nil
+	case "s3":
+		return newS3Uploader(cfg.S3, client)
+	case "cos":
+		return newCOSUploader(cfg.COS, client)
+	case "oss":
+		return newOSSUploader(cfg.OSS, client)
+	case "wechat":
+		return newWeChatUploader(wechat)
+	}
+	return nil, fmt.Errorf("未知的图床: %q", cfg.Provider)
+}
+
+// imageObjectKey 按图片内容生成对象名，同一张图片在多次转换中得到相同的地址
+func imageObjectKey(prefix string, data []byte, ext string) string {
+	sum := sha256.Sum256(data)
+	return prefix + hex.EncodeToString(sum[:16]) + ext
+}
+
+// imageContentType 返回图片扩展名对应的 Content-Type
+func imageContentType(ext string) string {
+	if t := mime.TypeByExtension(ext); t != "" {
+		return t
+	}
+	return "application/octet-stream"
+}
+
+// joinURL 把对象名拼接到基础地址后面，对象名中的每一段都做 URL 编码
+func joinURL(base, key string) string {
+	return strings.TrimRight(base, "/") + escapeObjectKey(key)
+}
+
+// escapeObjectKey 按 RFC 3986 编码对象名的每一段并加上前导 "/"，这也是签名时使用的规范路径
+func escapeObjectKey(key string) string {
+	segments := strings.Split(key, "/")
+	for i, s := range segments {
+		segments[i] = strings.ReplaceAll(url.PathEscape(s), "+", "%2B")
+	}
+	return "/" + strings.Join(segments, "/")
+}
+
+// uploadError 读取上传失败时的响应，便于排查签名或权限问题
+func uploadError(name string, resp *http.Response) error {
+	var body [512]byte
+	n, _ := resp.Body.Read(body[:])
+	return fmt.Errorf("上传到 %s 失败: %s %s", name, resp.Status, strings.TrimSpace(string(body[:n])))
+}
+
+// localUploader 把图片复制到本地目录 (如静态网站的 images 目录)，地址为 BaseURL 加文件名
+type localUploader struct {
+	cfg LocalUploadConfig
+}
+
+func (u *localUploader) Name() string {
+	return "local"
+}
+
+func (u *localUploader) Target() string {
+	return "local:" + u.cfg.Dir + "|" + u.cfg.BaseURL
+}
+
+func (u *localUploader) Upload(ctx context.Context, key string, data []byte, contentType string) (string, error) {
+	path := filepath.Join(u.cfg.Dir, filepath.FromSlash(key))
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return "", fmt.Errorf("无法创建目录: %v", err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return "", fmt.Errorf("无法写入图片: %v", err)
+	}
+	return joinURL(u.cfg.BaseURL, key), nil
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+)
+
+// cosUploader 通过腾讯云 COS 的 PUT Object 接口上传图片，使用 COS 的 HMAC-SHA1 请求签名
+type cosUploader struct {
+	cfg    COSConfig
+	client *http.Client
+}
+
+func newCOSUploader(cfg COSConfig, client *http.Client) (*cosUploader, error) {
+	if cfg.SecretID == "" {
+		cfg.SecretID = os.Getenv("COS_SECRET_ID")
+	}
+	if cfg.SecretKey == "" {
+		cfg.SecretKey = os.Getenv("COS_SECRET_KEY")
+	}
+	if cfg.Bucket == "" || cfg.Region == "" || cfg.SecretID == "" || cfg.SecretKey == "" {
+		return nil, fmt.Errorf("cos 图床需要配置 bucket (如 images-1250000000)、region、secret_id 与 secret_key")
+	}
+	return &cosUploader{cfg: cfg, client: client}, nil
+}
+
+func (u *cosUploader) Name() string {
+	return "cos"
+}
+
+func (u *cosUploader) Target() string {
+	return "cos:" + u.host() + "|" + u.cfg.BaseURL
+}
+
+func (u *cosUploader) host() string {
+	return u.cfg.Bucket + ".cos." + u.cfg.Region + ".myqcloud.com"
+}
+
+func (u *cosUploader) Upload(ctx context.Context, key string, data []byte, contentType string) (string, error) {
+	objectURL := "https://" + u.host() + escapeObjectKey(key)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, objectURL, bytes.NewReader(data))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", contentType)
+	u.sign(req, time.Now())
+
+	resp, err := u.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("上传到 cos 失败: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return "", uploadError("cos", resp)
+	}
+	if u.cfg.BaseURL != "" {
+		return joinURL(u.cfg.BaseURL, key), nil
+	}
+	return objectURL, nil
+}
+
+// sign 按 COS 请求签名规则添加 Authorization 头，只签名 host 头，签名有效期 10 分钟
+func (u *cosUploader) sign(req *http.Request, t time.Time) {
+	keyTime := fmt.Sprintf("%d;%d", t.Unix(), t.Add(10*time.Minute).Unix())
+	signKey := hex.EncodeToString(hmacSHA1([]byte(u.cfg.SecretKey), keyTime))
+
+	httpString := "put\n" +
+		req.URL.Path + "\n" +
+		"\n" +
+		"host=" + url.QueryEscape(req.URL.Host) + "\n"
+	stringToSign := "sha1\n" + keyTime + "\n" + sha1Hex([]byte(httpString)) + "\n"
+	signature := hex.EncodeToString(hmacSHA1([]byte(signKey), stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf(
+		"q-sign-algorithm=sha1&q-ak=%s&q-sign-time=%s&q-key-time=%s&q-header-list=host&q-url-param-list=&q-signature=%s",
+		u.cfg.SecretID, keyTime, keyTime, signature))
+}
+
+func sha1Hex(data []byte) string {
+	sum := sha1.Sum(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA1(key []byte, data string) []byte {
+	h := hmac.New(sha1.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+)
+
+// s3Uploader 通过 S3 PUT Object 接口上传图片，使用 AWS Signature Version 4 签名，
+// 兼容 AWS S3、MinIO、Cloudflare R2 等 S3 兼容存储
+type s3Uploader struct {
+	cfg      S3Config
+	endpoint *url.URL
+	client   *http.Client
+	// now 返回签名使用的时间，测试中替换为固定时间
+	now func() time.Time
+}
+
+func newS3Uploader(cfg S3Config, client *http.Client) (*s3Uploader, error) {
+	if cfg.AccessKey == "" {
+		cfg.AccessKey = os.Getenv("AWS_ACCESS_KEY_ID")
+	}
+	if cfg.SecretKey == "" {
+		cfg.SecretKey = os.Getenv("AWS_SECRET_ACCESS_KEY")
+	}
+	if cfg.Region == "" {
+		cfg.Region = "us-east-1"
+	}
+	if cfg.Endpoint == "" {
+		cfg.Endpoint = "https://s3." + cfg.Region + ".amazonaws.com"
+	}
+	if cfg.Bucket == "" || cfg.AccessKey == "" || cfg.SecretKey == "" {
+		return nil, fmt.Errorf("s3 图床需要配置 bucket、access_key 与 secret_key")
+	}
+	endpoint, err := url.Parse(cfg.Endpoint)
+	if err != nil || endpoint.Host == "" {
+		return nil, fmt.Errorf("无效的 s3 endpoint: %q", cfg.Endpoint)
+	}
+	return &s3Uploader{cfg: cfg, endpoint: endpoint, client: client, now: time.Now}, nil
+}
+
+func (u *s3Uploader) Name() string {
+	return "s3"
+}
+
+func (u *s3Uploader) Target() string {
+	return "s3:" + u.endpoint.Host + "/" + u.cfg.Bucket + "|" + u.cfg.BaseURL
+}
+
+// objectURL 返回对象地址，PathStyle 为 true 时使用 endpoint/bucket/key (MinIO 默认)，否则使用 bucket.endpoint/key
+func (u *s3Uploader) objectURL(key string) string {
+	if u.cfg.PathStyle {
+		return u.endpoint.Scheme + "://" + u.endpoint.Host + "/" + u.cfg.Bucket + escapeObjectKey(key)
+	}
+	return u.endpoint.Scheme + "://" + u.cfg.Bucket + "." + u.endpoint.Host + escapeObjectKey(key)
+}
+
+func (u *s3Uploader) Upload(ctx context.Context, key string, data []byte, contentType string) (string, error) {
+	objectURL := u.objectURL(key)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, objectURL, bytes.NewReader(data))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", contentType)
+	u.sign(req, data, u.now().UTC())
+
+	resp, err := u.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("上传到 s3 失败: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return "", uploadError("s3", resp)
+	}
+	if u.cfg.BaseURL != "" {
+		return joinURL(u.cfg.BaseURL, key), nil
+	}
+	return objectURL, nil
+}
+
+// sign 按 AWS Signature Version 4 为请求添加 Authorization 头
+func (u *s3Uploader) sign(req *http.Request, payload []byte, t time.Time) {
+	amzDate := t.Format("20060102T150405Z")
+	date := t.Format("20060102")
+	payloadHash := sha256Hex(payload)
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	const signedHeaders = "content-type;host;x-amz-content-sha256;x-amz-date"
+	canonicalRequest := req.Method + "\n" +
+		req.URL.EscapedPath() + "\n" +
+		"\n" +
+		"content-type:" + req.Header.Get("Content-Type") + "\n" +
+		"host:" + req.URL.Host + "\n" +
+		"x-amz-content-sha256:" + payloadHash + "\n" +
+		"x-amz-date:" + amzDate + "\n" +
+		"\n" +
+		signedHeaders + "\n" +
+		payloadHash
+
+	scope := date + "/" + u.cfg.Region + "/s3/aws4_request"
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + sha256Hex([]byte(canonicalRequest))
+
+	key := sigV4SigningKey(u.cfg.SecretKey, date, u.cfg.Region, "s3")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		u.cfg.AccessKey, scope, signedHeaders, signature))
+}
+
+// sigV4SigningKey 按日期、区域和服务逐级派生 Signature Version 4 的签名密钥
+func sigV4SigningKey(secret, date, region, service string) []byte {
+	key := hmacSHA256([]byte("AWS4"+secret), date)
+	key = hmacSHA256(key, region)
+	key = hmacSHA256(key, service)
+	return hmacSHA256(key, "aws4_request")
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"context"
+	"encoding/hex"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+const (
+	testAccessKey = "AKIDEXAMPLE"
+	testSecretKey = "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY"
+)
+
+// AWS 文档 "Examples of how to derive a signing key for Signature Version 4" 中的示例
+func TestSigV4SigningKey(t *testing.T) {
+	got := hex.EncodeToString(sigV4SigningKey(testSecretKey, "20120215", "us-east-1", "iam"))
+	want := "f4780e2d9f65fa895f9c67b32ce1baf0b0d8a43505a000a1a9e090d414db404d"
+	if got != want {
+		t.Errorf("签名密钥 = %s, 期望 %s", got, want)
+	}
+}
+
+func TestS3Sign(t *testing.T) {
+	u, err := newS3Uploader(S3Config{
+		Endpoint:  "http://127.0.0.1:9000",
+		Bucket:    "images",
+		AccessKey: testAccessKey,
+		SecretKey: testSecretKey,
+		PathStyle: true,
+	}, http.DefaultClient)
+	if err != nil {
+		t.Fatal(err)
+	}
+	payload := []byte("hello")
+	req, err := http.NewRequest(http.MethodPut, u.objectURL("wechat/ab cd.png"), strings.NewReader(string(payload)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "image/png")
+	u.sign(req, payload, time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC))
+
+	if got := req.URL.EscapedPath(); got != "/images/wechat/ab%20cd.png" {
+		t.Errorf("请求路径 = %s", got)
+	}
+	if got := req.Header.Get("X-Amz-Date"); got != "20240501T120000Z" {
+		t.Errorf("X-Amz-Date = %s", got)
+	}
+	if got := req.Header.Get("X-Amz-Content-Sha256"); got != "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824" {
+		t.Errorf("X-Amz-Content-Sha256 = %s", got)
+	}
+	// 期望值由独立的 Signature Version 4 实现计算
+	want := "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20240501/us-east-1/s3/aws4_request, " +
+		"SignedHeaders=content-type;host;x-amz-content-sha256;x-amz-date, " +
+		"Signature=5300d5c25721e4552c738446607f306735e1b4390650675e3ed6a64524a8da31"
+	if got := req.Header.Get("Authorization"); got != want {
+		t.Errorf("Authorization = %s\n期望 %s", got, want)
+	}
+}
+
+// newMinIOStandIn 启动一个模拟 MinIO 的服务器，检查 PUT Object 请求。客户端把 minio.test:9000 的连接转发到该服务器，
+// 使签名中的 host 固定，Authorization 与独立的 Signature Version 4 实现计算的结果比较
+func newMinIOStandIn(t *testing.T, wantPath string, wantBody []byte, wantAuth string) (*httptest.Server, *http.Client) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		switch {
+		case r.Method != http.MethodPut:
+			t.Errorf("方法 = %s, 期望 PUT", r.Method)
+		case r.Host != "minio.test:9000":
+			t.Errorf("Host = %s", r.Host)
+		case r.URL.EscapedPath() != wantPath:
+			t.Errorf("路径 = %s, 期望 %s", r.URL.EscapedPath(), wantPath)
+		case string(body) != string(wantBody):
+			t.Errorf("请求体 = %q", body)
+		case r.Header.Get("Content-Type") != "image/png":
+			t.Errorf("Content-Type = %s", r.Header.Get("Content-Type"))
+		case r.Header.Get("X-Amz-Date") != "20240501T120000Z":
+			t.Errorf("X-Amz-Date = %s", r.Header.Get("X-Amz-Date"))
+		case r.Header.Get("X-Amz-Content-Sha256") != sha256Hex(body):
+			t.Errorf("X-Amz-Content-Sha256 = %s", r.Header.Get("X-Amz-Content-Sha256"))
+		}
+		if got := r.Header.Get("Authorization"); got != wantAuth {
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = w.Write([]byte("SignatureDoesNotMatch"))
+			t.Errorf("Authorization = %s\n期望 %s", got, wantAuth)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	client := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, network, srv.Listener.Addr().String())
+		},
+	}}
+	return srv, client
+}
+
+func TestS3UploadToMinIOStandIn(t *testing.T) {
+	data := []byte("\x89PNG fake image")
+	// 期望值由独立的 Signature Version 4 实现计算
+	wantAuth := "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20240501/us-east-1/s3/aws4_request, " +
+		"SignedHeaders=content-type;host;x-amz-content-sha256;x-amz-date, " +
+		"Signature=321e92f02016885a8ae685f80f6da32f2f620e8f2444bdbe6372a317826427f4"
+	srv, client := newMinIOStandIn(t, "/images/wechat/ab%20cd.png", data, wantAuth)
+	defer srv.Close()
+
+	tests := []struct {
+		name    string
+		baseURL string
+		want    string
+	}{
+		{"对象地址", "", "http://minio.test:9000/images/wechat/ab%20cd.png"},
+		{"CDN 地址", "https://cdn.example.com/", "https://cdn.example.com/wechat/ab%20cd.png"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u, err := newS3Uploader(S3Config{
+				Endpoint:  "http://minio.test:9000",
+				Bucket:    "images",
+				AccessKey: testAccessKey,
+				SecretKey: testSecretKey,
+				PathStyle: true,
+				BaseURL:   tt.baseURL,
+			}, client)
+			if err != nil {
+				t.Fatal(err)
+			}
+			u.now = func() time.Time { return time.Date(2024, 5, 1, 20, 0, 0, 0, time.FixedZone("CST", 8*3600)) }
+			got, err := u.Upload(context.Background(), "wechat/ab cd.png", data, "image/png")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("图片地址 = %s, 期望 %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestS3UploadError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+		_, _ = w.Write([]byte("AccessDenied"))
+	}))
+	defer srv.Close()
+
+	u, err := newS3Uploader(S3Config{
+		Endpoint:  srv.URL,
+		Bucket:    "images",
+		AccessKey: testAccessKey,
+		SecretKey: testSecretKey,
+		PathStyle: true,
+	}, srv.Client())
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = u.Upload(context.Background(), "a.png", []byte("x"), "image/png")
+	if err == nil || !strings.Contains(err.Error(), "403") || !strings.Contains(err.Error(), "AccessDenied") {
+		t.Errorf("错误 = %v, 期望包含状态码与响应内容", err)
+	}
+}
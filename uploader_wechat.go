@@ -0,0 +1,39 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"path"
+)
+
+// wechatUploader 通过公众号的 uploadimg 接口上传图片，得到的地址可以直接用于图文消息正文
+type wechatUploader struct {
+	client *wechatClient
+}
+
+func newWeChatUploader(cfg WeChatConfig) (*wechatUploader, error) {
+	client, err := newWeChatClient(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("wechat 图床: %v", err)
+	}
+	return &wechatUploader{client: client}, nil
+}
+
+func (u *wechatUploader) Name() string {
+	return "wechat"
+}
+
+func (u *wechatUploader) Target() string {
+	return "wechat:" + u.client.cfg.AppID
+}
+
+// Upload 上传图片。uploadimg 只接受不超过 1MB 的 jpg/png，images 的默认配置已经满足这一限制
+func (u *wechatUploader) Upload(ctx context.Context, key string, data []byte, contentType string) (string, error) {
+	if contentType != "image/jpeg" && contentType != "image/png" {
+		return "", fmt.Errorf("上传到 wechat 失败: 只支持 jpg/png 图片, 得到 %s", contentType)
+	}
+	if len(data) > 1<<20 {
+		return "", fmt.Errorf("上传到 wechat 失败: 图片 %s 超过 1MB，请调小 images.max_bytes", formatBytes(len(data)))
+	}
+	return u.client.uploadImage(ctx, path.Base(key), data)
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// newWeChatStandIn 启动一个模拟公众号接口的服务器：/token 返回 access_token，/media/uploadimg 检查上传的图片
+func newWeChatStandIn(t *testing.T, wantBody []byte, uploadResp string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/token":
+			if r.URL.Query().Get("appid") != "wxtest" || r.URL.Query().Get("secret") != "secret" {
+				t.Errorf("获取 access_token 的参数 = %s", r.URL.RawQuery)
+			}
+			_, _ = w.Write([]byte(`{"access_token":"TOKEN","expires_in":7200}`))
+		case "/media/uploadimg":
+			if r.Method != http.MethodPost || r.URL.Query().Get("access_token") != "TOKEN" {
+				t.Errorf("请求 = %s %s", r.Method, r.URL)
+			}
+			file, header, err := r.FormFile("media")
+			if err != nil {
+				t.Errorf("缺少 media 文件: %v", err)
+				return
+			}
+			body, _ := io.ReadAll(file)
+			if header.Filename != "ab.png" || string(body) != string(wantBody) {
+				t.Errorf("上传的文件 = %s %q", header.Filename, body)
+			}
+			_, _ = w.Write([]byte(uploadResp))
+		default:
+			t.Errorf("未知的接口: %s", r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+}
+
+func newTestWeChatUploader(t *testing.T, api string) *wechatUploader {
+	u, err := newWeChatUploader(WeChatConfig{AppID: "wxtest", AppSecret: "secret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	u.client.api = api
+	return u
+}
+
+func TestWeChatUpload(t *testing.T) {
+	data := []byte("\x89PNG fake image")
+	srv := newWeChatStandIn(t, data, `{"url":"http://mmbiz.qpic.cn/mmbiz_png/abc/0"}`)
+	defer srv.Close()
+
+	u := newTestWeChatUploader(t, srv.URL)
+	got, err := u.Upload(context.Background(), "wechat/ab.png", data, "image/png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != "http://mmbiz.qpic.cn/mmbiz_png/abc/0" {
+		t.Errorf("图片地址 = %s", got)
+	}
+	if u.Target() != "wechat:wxtest" {
+		t.Errorf("Target = %s", u.Target())
+	}
+}
+
+func TestWeChatUploadErrors(t *testing.T) {
+	srv := newWeChatStandIn(t, []byte("x"), `{"errcode":40005,"errmsg":"invalid file type"}`)
+	defer srv.Close()
+	u := newTestWeChatUploader(t, srv.URL)
+
+	tests := []struct {
+		name        string
+		data        []byte
+		contentType string
+		want        string
+	}{
+		{"接口错误", []byte("x"), "image/png", "40005"},
+		{"格式", []byte("x"), "image/gif", "jpg/png"},
+		{"大小", make([]byte, 1<<20+1), "image/jpeg", "1MB"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := u.Upload(context.Background(), "wechat/ab.png", tt.data, tt.contentType)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("错误 = %v, 期望包含 %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewWeChatUploaderRequiresCredentials(t *testing.T) {
+	t.Setenv("WECHAT_APPID", "")
+	t.Setenv("WECHAT_APPSECRET", "")
+	if _, err := newUploader(UploadConfig{Provider: "wechat"}, WeChatConfig{}); err == nil {
+		t.Error("缺少 app_id 与 app_secret 时应该报错")
+	}
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+	"os"
+	"regexp"
+	"strings"
+
+	"golang.org/x/image/draw"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/math/fixed"
+)
+
+// 在 Google Docs 的替代文字 (标题或说明) 中写上这些标记的图片不加水印，标记不会出现在 alt 中
+var reNoWatermark = regexp.MustCompile(`(?i)\s*\[(no-?watermark|无水印)\]\s*`)
+
+// stripNoWatermark 去掉替代文字中的不加水印标记，返回去掉后的文字以及是否包含标记
+func stripNoWatermark(s string) (string, bool) {
+	if !reNoWatermark.MatchString(s) {
+		return s, false
+	}
+	return strings.TrimSpace(reNoWatermark.ReplaceAllString(s, " ")), true
+}
+
+// watermarker 把文字或 Logo 水印叠加到图片上
+type watermarker struct {
+	cfg  WatermarkConfig
+	font *opentype.Font
+	logo image.Image
+}
+
+// newWatermarker 加载水印所需的字体与 Logo，没有配置文字和 Logo 时返回 nil
+func newWatermarker(cfg WatermarkConfig) (*watermarker, error) {
+	if cfg.Text == "" && cfg.Logo == "" {
+		return nil, nil
+	}
+	w := &watermarker{cfg: cfg}
+	if cfg.Logo != "" {
+		f, err := os.Open(cfg.Logo)
+		if err != nil {
+			return nil, fmt.Errorf("无法读取水印 Logo: %v", err)
+		}
+		defer f.Close()
+		if w.logo, _, err = image.Decode(f); err != nil {
+			return nil, fmt.Errorf("无法解码水印 Logo: %v", err)
+		}
+		return w, nil
+	}
+
+	// 内置的 Go 字体只包含西文字符，中文水印需要配置支持中文的字体文件
+	fontData := goregular.TTF
+	if cfg.Font != "" {
+		b, err := os.ReadFile(cfg.Font)
+		if err != nil {
+			return nil, fmt.Errorf("无法读取水印字体: %v", err)
+		}
+		fontData = b
+	}
+	f, err := opentype.Parse(fontData)
+	if err != nil {
+		return nil, fmt.Errorf("无法解析水印字体: %v", err)
+	}
+	w.font = f
+	return w, nil
+}
+
+// apply 返回加上水印的图片，宽度小于 MinWidth 的图片原样返回
+func (w *watermarker) apply(src image.Image) (image.Image, error) {
+	b := src.Bounds()
+	if b.Dx() < w.cfg.MinWidth {
+		return src, nil
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(dst, dst.Bounds(), src, b.Min, draw.Src)
+
+	mark, err := w.render(int(math.Round(float64(b.Dx()) * w.cfg.Scale)))
+	if err != nil {
+		return nil, err
+	}
+	at := w.position(dst.Bounds(), mark.Bounds())
+	alpha := image.NewUniform(color.Alpha{A: uint8(math.Round(w.cfg.Opacity * 255))})
+	draw.DrawMask(dst, mark.Bounds().Add(at), mark, image.Point{}, alpha, image.Point{}, draw.Over)
+	return dst, nil
+}
+
+// render 生成宽度为 width 的水印图案 (不含透明度)
+func (w *watermarker) render(width int) (image.Image, error) {
+	width = max(width, 1)
+	if w.logo != nil {
+		lb := w.logo.Bounds()
+		height := max(1, lb.Dy()*width/lb.Dx())
+		dst := image.NewRGBA(image.Rect(0, 0, width, height))
+		draw.CatmullRom.Scale(dst, dst.Bounds(), w.logo, lb, draw.Src, nil)
+		return dst, nil
+	}
+
+	// 先按 100pt 测量文字宽度，再换算出使文字宽度等于 width 的字号
+	const probeSize = 100
+	size := probeSize * float64(width) / w.measure(probeSize)
+	face, err := opentype.NewFace(w.font, &opentype.FaceOptions{Size: size, DPI: 72, Hinting: font.HintingNone})
+	if err != nil {
+		return nil, fmt.Errorf("无法创建水印字体: %v", err)
+	}
+	defer face.Close()
+
+	metrics := face.Metrics()
+	ascent, descent := metrics.Ascent.Ceil(), metrics.Descent.Ceil()
+	// 文字下方叠一层半透明阴影，在浅色图片上也能看清
+	shadow := max(1, int(size/24))
+	textColor := color.Color(color.White)
+	if r, g, bl, ok := parseHexColor(w.cfg.Color); ok {
+		textColor = color.RGBA{uint8(r), uint8(g), uint8(bl), 255}
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, width+shadow, ascent+descent+shadow))
+	d := &font.Drawer{Dst: dst, Face: face}
+	for _, layer := range []struct {
+		offset int
+		color  color.Color
+	}{{shadow, color.RGBA{0, 0, 0, 96}}, {0, textColor}} {
+		d.Src = image.NewUniform(layer.color)
+		d.Dot = fixed.P(layer.offset, ascent+layer.offset)
+		d.DrawString(w.cfg.Text)
+	}
+	return dst, nil
+}
+
+func (w *watermarker) measure(size float64) float64 {
+	face, err := opentype.NewFace(w.font, &opentype.FaceOptions{Size: size, DPI: 72, Hinting: font.HintingNone})
+	if err != nil {
+		return size
+	}
+	defer face.Close()
+	if adv := font.MeasureString(face, w.cfg.Text); adv > 0 {
+		return float64(adv) / 64
+	}
+	return size
+}
+
+// position 按配置的位置返回水印左上角的坐标，与图片边缘保留 3% 的边距
+func (w *watermarker) position(img, mark image.Rectangle) image.Point {
+	margin := int(math.Round(float64(min(img.Dx(), img.Dy())) * 0.03))
+	left, top := margin, margin
+	right, bottom := img.Dx()-mark.Dx()-margin, img.Dy()-mark.Dy()-margin
+	switch w.cfg.Position {
+	case "top-left":
+		return image.Pt(left, top)
+	case "top-right":
+		return image.Pt(right, top)
+	case "bottom-left":
+		return image.Pt(left, bottom)
+	case "center":
+		return image.Pt((img.Dx()-mark.Dx())/2, (img.Dy()-mark.Dy())/2)
+	}
+	return image.Pt(right, bottom)
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+)
+
+const wechatAPI = "https://api.weixin.qq.com/cgi-bin"
+
+// wechatClient 调用微信公众号接口，access_token 在有效期内复用
+type wechatClient struct {
+	cfg    WeChatConfig
+	client *http.Client
+	// api 是接口地址，测试中替换为本地服务器
+	api     string
+	token   string
+	expires time.Time
+}
+
+// wechatError 是微信接口在出错时返回的 errcode 与 errmsg
+type wechatError struct {
+	ErrCode int    `json:"errcode"`
+	ErrMsg  string `json:"errmsg"`
+}
+
+func (e wechatError) err() error {
+	if e.ErrCode == 0 {
+		return nil
+	}
+	return fmt.Errorf("微信接口错误 %d: %s", e.ErrCode, e.ErrMsg)
+}
+
+// newWeChatClient 创建微信接口客户端，AppID 与 AppSecret 为空时从 WECHAT_APPID 与 WECHAT_APPSECRET 读取
+func newWeChatClient(cfg WeChatConfig) (*wechatClient, error) {
+	if cfg.AppID == "" {
+		cfg.AppID = os.Getenv("WECHAT_APPID")
+	}
+	if cfg.AppSecret == "" {
+		cfg.AppSecret = os.Getenv("WECHAT_APPSECRET")
+	}
+	if cfg.AppID == "" || cfg.AppSecret == "" {
+		return nil, fmt.Errorf("需要在配置文件的 wechat 中设置 app_id 与 app_secret")
+	}
+	return &wechatClient{cfg: cfg, client: &http.Client{Timeout: 60 * time.Second}, api: wechatAPI}, nil
+}
+
+// accessToken 获取接口调用凭证，提前一分钟刷新
+func (c *wechatClient) accessToken(ctx context.Context) (string, error) {
+	if c.token != "" && time.Now().Before(c.expires) {
+		return c.token, nil
+	}
+	q := url.Values{
+		"grant_type": {"client_credential"},
+		"appid":      {c.cfg.AppID},
+		"secret":     {c.cfg.AppSecret},
+	}
+	var resp struct {
+		wechatError
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := c.do(ctx, http.MethodGet, c.api+"/token?"+q.Encode(), "", nil, &resp); err != nil {
+		return "", fmt.Errorf("获取 access_token 失败: %v", err)
+	}
+	c.token = resp.AccessToken
+	c.expires = time.Now().Add(time.Duration(resp.ExpiresIn-60) * time.Second)
+	return c.token, nil
+}
+
+// addMaterial 上传永久素材，kind 为 "image" 或 "thumb"，返回 media_id 与素材地址
+func (c *wechatClient) addMaterial(ctx context.Context, kind, filename string, data []byte) (mediaID, mediaURL string, err error) {
+	token, err := c.accessToken(ctx)
+	if err != nil {
+		return "", "", err
+	}
+	body, contentType, err := mediaForm(filename, data)
+	if err != nil {
+		return "", "", err
+	}
+
+	q := url.Values{"access_token": {token}, "type": {kind}}
+	var resp struct {
+		wechatError
+		MediaID string `json:"media_id"`
+		URL     string `json:"url"`
+	}
+	if err := c.do(ctx, http.MethodPost, c.api+"/material/add_material?"+q.Encode(), contentType, body, &resp); err != nil {
+		return "", "", fmt.Errorf("上传永久素材失败: %v", err)
+	}
+	return resp.MediaID, resp.URL, nil
+}
+
+// uploadImage 通过 uploadimg 接口上传图文消息内的图片 (jpg/png，不超过 1MB)，返回 mmbiz.qpic.cn 上的图片地址。
+// 这些图片不占用素材库的数量限制
+func (c *wechatClient) uploadImage(ctx context.Context, filename string, data []byte) (string, error) {
+	token, err := c.accessToken(ctx)
+	if err != nil {
+		return "", err
+	}
+	body, contentType, err := mediaForm(filename, data)
+	if err != nil {
+		return "", err
+	}
+
+	q := url.Values{"access_token": {token}}
+	var resp struct {
+		wechatError
+		URL string `json:"url"`
+	}
+	if err := c.do(ctx, http.MethodPost, c.api+"/media/uploadimg?"+q.Encode(), contentType, body, &resp); err != nil {
+		return "", fmt.Errorf("上传图文消息图片失败: %v", err)
+	}
+	return resp.URL, nil
+}
+
+// mediaForm 把文件编码为字段名为 media 的 multipart 表单，返回表单内容与 Content-Type
+func mediaForm(filename string, data []byte) (*bytes.Buffer, string, error) {
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	part, err := mw.CreateFormFile("media", filename)
+	if err != nil {
+		return nil, "", err
+	}
+	if _, err := part.Write(data); err != nil {
+		return nil, "", err
+	}
+	if err := mw.Close(); err != nil {
+		return nil, "", err
+	}
+	return &body, mw.FormDataContentType(), nil
+}
+
+// do 发送请求并解析 JSON 响应，out 需要内嵌 wechatError
+func (c *wechatClient) do(ctx context.Context, method, url, contentType string, body *bytes.Buffer, out interface{ err() error }) error {
+	var req *http.Request
+	var err error
+	if body != nil {
+		req, err = http.NewRequestWithContext(ctx, method, url, body)
+	} else {
+		req, err = http.NewRequestWithContext(ctx, method, url, nil)
+	}
+	if err != nil {
+		return err
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("HTTP %s", resp.Status)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("无法解析响应: %v", err)
+	}
+	return out.err()
+}